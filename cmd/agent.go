@@ -0,0 +1,58 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// agentCmd represents the agent command
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep a 1password client and a cache of vault and item lists for other envop commands to use",
+	Long: `Run an agent on a unix socket that only the current user can access. While it is running, other envop 
+commands and the Go library send their calls through it, and the vault and item lists are cached for --ttl. Items are always fetched fresh.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := cmd.Flags().GetString("agent-socket")
+		if err != nil {
+			return err
+		}
+
+		ttl, err := cmd.Flags().GetDuration("ttl")
+		if err != nil {
+			return err
+		}
+
+		callTimeout, err := cmd.Flags().GetDuration("call-timeout")
+		if err != nil {
+			return err
+		}
+
+		if socketPath == "" {
+			return service.ErrNoAgentSocket
+		}
+
+		client, err := newServiceClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		agent := service.NewAgent(client, ttl)
+		agent.CallTimeout = callTimeout
+
+		fmt.Printf("agent listening on %s\n", socketPath)
+
+		return agent.Serve(cmd.Context(), socketPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.Flags().Duration("ttl", 5*time.Minute, "How long to cache the vault and item lists")
+	agentCmd.Flags().Duration("call-timeout", service.DefaultAgentCallTimeout, "How long the agent spends on a single call to 1password")
+}
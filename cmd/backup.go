@@ -0,0 +1,128 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// backupCmd dumps a whole vault into an encrypted archive
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up every item in a vault into an age encrypted archive",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaultName, err := cmd.Flags().GetString("vault")
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		recipients, err := backupRecipients(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
+		if err != nil {
+			return err
+		}
+
+		backup, err := service.BackupVault(cmd.Context(), client, vault)
+		if err != nil {
+			return err
+		}
+
+		err = service.WriteBackupFile(output, backup, recipients...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("backed up %d items from %s to %s\n", len(backup.Items), vault.Title, output)
+
+		return nil
+	},
+}
+
+// backupPassphrase reads the passphrase from --passphrase-file or ENVOP_BACKUP_PASSPHRASE
+func backupPassphrase(cmd *cobra.Command) (string, error) {
+	passphraseFile, err := cmd.Flags().GetString("passphrase-file")
+	if err != nil {
+		return "", err
+	}
+
+	if passphraseFile != "" {
+		raw, err := service.ReadPrivateFile(passphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(raw), nil
+	}
+
+	return os.Getenv("ENVOP_BACKUP_PASSPHRASE"), nil
+}
+
+// backupRecipients returns the age recipients, or a passphrase recipient when there are none
+func backupRecipients(cmd *cobra.Command) ([]age.Recipient, error) {
+	keys, err := cmd.Flags().GetStringSlice("recipient")
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]age.Recipient, 0, len(keys))
+	for _, key := range keys {
+		recipient, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) > 0 {
+		return recipients, nil
+	}
+
+	passphrase, err := backupPassphrase(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("set --recipient, --passphrase-file or ENVOP_BACKUP_PASSPHRASE to encrypt the backup")
+	}
+
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return []age.Recipient{recipient}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.Flags().String("vault", "", "The 1password vault to back up")
+	backupCmd.MarkFlagRequired("vault")
+
+	backupCmd.Flags().StringP("output", "o", "", "The archive to write, eg backup.age")
+	backupCmd.MarkFlagRequired("output")
+
+	backupCmd.Flags().StringSlice("recipient", nil, "The age public keys to encrypt to")
+	backupCmd.Flags().String("passphrase-file", "", "Encrypt with the passphrase in this file instead of age keys")
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// cloneCmd copies a whole item, every section and field, to a new item
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Copy the specified item, with all its sections and fields, to a new item",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		sourceVaultName, err := cmd.Flags().GetString("source-vault")
+		if err != nil {
+			return err
+		}
+
+		sourceItemName, err := cmd.Flags().GetString("source-item")
+		if err != nil {
+			return err
+		}
+
+		destinationVaultName, _ := cmd.Flags().GetString("destination-vault")
+		if destinationVaultName == "" {
+			destinationVaultName = sourceVaultName
+		}
+
+		destinationItemName, _ := cmd.Flags().GetString("destination-item")
+		if destinationItemName == "" {
+			destinationItemName = sourceItemName
+		}
+
+		if destinationVaultName == sourceVaultName && destinationItemName == sourceItemName {
+			return fmt.Errorf("cloning within vault %s needs a --destination-item other than %s", sourceVaultName, sourceItemName)
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		sourceVault, err := service.FindVaultWithName(cmd.Context(), client, sourceVaultName)
+		if err != nil {
+			return err
+		}
+
+		sourceItem, err := service.FindItemWithName(cmd.Context(), client, sourceVault, sourceItemName)
+		if err != nil {
+			return err
+		}
+
+		if sourceItem == nil {
+			return fmt.Errorf("Item %s not found in vault %s", sourceItemName, sourceVaultName)
+		}
+
+		destinationVault, err := service.FindVaultWithName(cmd.Context(), client, destinationVaultName)
+		if err != nil {
+			return err
+		}
+
+		if len(sourceItem.Files) > 0 || sourceItem.Document != nil {
+			fmt.Printf("warning: files attached to %s are not cloned\n", sourceItem.Title)
+		}
+
+		if dryRun {
+			params, err := service.PlanClone(cmd.Context(), client, sourceItem, destinationVault, destinationItemName)
+			if err != nil {
+				return err
+			}
+
+			printCloneParams(params, destinationVault)
+			return nil
+		}
+
+		item, err := service.CloneItem(cmd.Context(), client, sourceItem, destinationVault, destinationItemName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("item created: %s (%s)\n", item.Title, item.ID)
+
+		return nil
+	},
+}
+
+// printCloneParams previews the item a clone would create, without the values
+func printCloneParams(params onepassword.ItemCreateParams, vault *onepassword.VaultOverview) {
+	fmt.Printf("would create %s item %s in vault %s\n", params.Category, params.Title, vault.Title)
+
+	for _, field := range params.Fields {
+		if field.SectionID == nil {
+			fmt.Printf("  %s (%s)\n", field.Title, field.FieldType)
+		}
+	}
+
+	for _, section := range params.Sections {
+		fmt.Printf("  [%s]\n", section.Title)
+		for _, field := range params.Fields {
+			if field.SectionID != nil && *field.SectionID == section.ID {
+				fmt.Printf("    %s (%s)\n", field.Title, field.FieldType)
+			}
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().String("source-vault", "", "The 1password vault to clone from")
+	cloneCmd.MarkFlagRequired("source-vault")
+
+	cloneCmd.Flags().String("source-item", "", "The name of the item to clone")
+	cloneCmd.MarkFlagRequired("source-item")
+
+	cloneCmd.Flags().String("destination-vault", "", "The 1password vault to clone to (default is the source vault)")
+
+	cloneCmd.Flags().String("destination-item", "", "The name of the new item (default is the source item name)")
+	cloneCmd.Flags().Bool("dry-run", false, "Show what would be created without creating it")
+}
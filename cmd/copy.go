@@ -46,22 +46,17 @@ var copyCmd = &cobra.Command{
 			destinationSectionName = sourceSectionName
 		}
 
-		token, err := cmd.Flags().GetString("service-account")
+		client, err := newClient(cmd)
 		if err != nil {
 			return err
 		}
 
-		client, err := service.NewClientFromToken(token)
+		sourceVault, err := service.FindVaultWithName(cmd.Context(), client, sourceVaultName)
 		if err != nil {
 			return err
 		}
 
-		sourceVault, err := service.FindVaultWithName(client, sourceVaultName)
-		if err != nil {
-			return err
-		}
-
-		sourceItem, err := service.FindItemWithName(client, sourceVault, sourceItemName)
+		sourceItem, err := service.FindItemWithName(cmd.Context(), client, sourceVault, sourceItemName)
 		if err != nil {
 			return err
 		}
@@ -70,17 +65,25 @@ var copyCmd = &cobra.Command{
 			return fmt.Errorf("Item %s not found in vault %s", sourceItemName, sourceItemName)
 		}
 
-		destinationVault, err := service.FindVaultWithName(client, destinationVaultName)
+		destinationVault, err := service.FindVaultWithName(cmd.Context(), client, destinationVaultName)
 		if err != nil {
 			return err
 		}
 
-		destinationItem, err := service.FindItemWithName(client, destinationVault, destinationItemName)
+		destinationItem, err := service.FindItemWithName(cmd.Context(), client, destinationVault, destinationItemName)
 		if err != nil {
 			return err
 		}
 
+		if destinationItem == nil {
+			destinationItem, err = service.CreateItem(cmd.Context(), client, destinationVault, destinationItemName, destinationSectionName)
+			if err != nil {
+				return err
+			}
+		}
+
 		return service.CopySection(
+			cmd.Context(),
 			client,
 			sourceItem,
 			sourceSectionName,
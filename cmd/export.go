@@ -4,6 +4,14 @@ Copyright © 2025 John Lennard <john@yakmoo.se>
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
 	"github.com/spf13/cobra"
 	"github.com/yakmoose/envop/service"
 )
@@ -33,17 +41,22 @@ var exportCmd = &cobra.Command{
 			return err
 		}
 
-		token, err := cmd.Flags().GetString("service-account")
+		client, err := newReadClient(cmd)
 		if err != nil {
 			return err
 		}
 
-		client, err := service.NewClientFromToken(token)
+		itemGlob, err := cmd.Flags().GetString("item-glob")
 		if err != nil {
 			return err
 		}
 
+		if itemGlob != "" {
+			return bulkExport(cmd, client, vaultName, itemGlob, sectionName)
+		}
+
 		env, err := service.ReadOnePassword(
+			cmd.Context(),
 			client,
 			vaultName,
 			itemName,
@@ -54,24 +67,184 @@ var exportCmd = &cobra.Command{
 			return err
 		}
 
+		err = validateSchema(cmd, sectionName, env)
+		if err != nil {
+			return err
+		}
+
 		format, err := cmd.Flags().GetString("format")
 		if err != nil {
 			return err
 		}
 
-		switch format {
-		case "json":
-			err = service.WriteJSON(envFile, env)
-		case "env":
-			err = service.WriteEnv(envFile, env)
-		case "tfvars", "hcl", "tfvar":
-			err = service.WriteHcl(envFile, env)
+		watch, err := cmd.Flags().GetBool("watch")
+		if err != nil {
+			return err
+		}
+
+		if !watch {
+			return service.WriteFormat(envFile, format, env)
+		}
+
+		if envFile == "" {
+			return fmt.Errorf("--watch needs an --env-file to keep in sync")
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			return err
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be more than 0, got %s", interval)
+		}
+
+		onChange, err := cmd.Flags().GetString("on-change")
+		if err != nil {
+			return err
+		}
+
+		signalPid, err := cmd.Flags().GetInt("signal-pid")
+		if err != nil {
+			return err
+		}
+
+		signalName, err := cmd.Flags().GetString("signal")
+		if err != nil {
+			return err
+		}
+
+		signal, err := service.ParseSignal(signalName)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		err = service.WriteAtomic(envFile, format, env)
+		if err != nil {
+			return err
+		}
+
+		watcher := service.Watch{
+			Vault:    vaultName,
+			Item:     itemName,
+			Sections: []string{sectionName},
+			Interval: interval,
+			Failed: func(err error) {
+				fmt.Fprintf(os.Stderr, "could not read %s: %v\n", itemName, err)
+			},
+		}
+
+		return watcher.Run(cmd.Context(), client, env, func(env map[string]any) error {
+			err := validateSchema(cmd, sectionName, env)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "not updating %s: %v\n", envFile, err)
+				return nil
+			}
+
+			err = service.WriteAtomic(envFile, format, env)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "%s updated\n", envFile)
+			notifyChange(cmd, onChange, signalPid, signal)
+			return nil
+		})
 	},
 }
 
+// bulkExport exports every section of the items matching the glob into --out-dir, and prints a summary
+func bulkExport(cmd *cobra.Command, client *onepassword.Client, vaultName string, itemGlob string, sectionName string) error {
+	outDir, err := cmd.Flags().GetString("out-dir")
+	if err != nil {
+		return err
+	}
+
+	if outDir == "" {
+		return fmt.Errorf("--item-glob needs an --out-dir to export to")
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+
+	schemaPath, err := cmd.Flags().GetString("schema")
+	if err != nil {
+		return err
+	}
+
+	export := service.BulkExport{Glob: itemGlob, Section: sectionName, OutDir: outDir, Format: format}
+	if schemaPath != "" {
+		schema, err := service.ReadSchema(schemaPath)
+		if err != nil {
+			return err
+		}
+		export.Validate = schema.Validate
+	}
+
+	vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
+	if err != nil {
+		return err
+	}
+
+	results, err := export.Run(cmd.Context(), client, vault)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no items matching %s found in vault %s", itemGlob, vaultName)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ITEM\tSECTION\tKEYS\tRESULT")
+
+	items := make(map[string]bool)
+	failed := 0
+	for _, result := range results {
+		items[result.Item] = true
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\t%s\t-\t%v\n", result.Item, result.Section, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", result.Item, result.Section, result.Keys, result.Path)
+	}
+	w.Flush()
+
+	fmt.Printf("\nexported %d of %d sections from %d items\n", len(results)-failed, len(results), len(items))
+	if failed > 0 {
+		return fmt.Errorf("%d sections could not be exported", failed)
+	}
+	return nil
+}
+
+// notifyChange runs the hook command and signals the process after the exported file changes, failures
+// are reported but don't stop the watch
+func notifyChange(cmd *cobra.Command, onChange string, pid int, signal syscall.Signal) {
+	if onChange != "" {
+		hook := exec.CommandContext(cmd.Context(), "/bin/sh", "-c", onChange)
+		hook.Env = service.WithoutTokens(os.Environ())
+		hook.Stdout = os.Stdout
+		hook.Stderr = os.Stderr
+
+		if err := hook.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "on-change command failed: %v\n", err)
+		}
+	}
+
+	if pid != 0 {
+		process, err := os.FindProcess(pid)
+		if err == nil {
+			err = process.Signal(signal)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not signal %d: %v\n", pid, err)
+		}
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(exportCmd)
 	exportCmd.Flags().String("env-file", "", "The file to save to")
@@ -80,10 +253,23 @@ func init() {
 	exportCmd.MarkFlagRequired("vault")
 
 	exportCmd.Flags().String("item", "", "The name of the item to save")
-	exportCmd.MarkFlagRequired("item")
+	exportCmd.Flags().String("item-glob", "", "Export every item matching this glob, eg 'svc-*', into --out-dir")
+	exportCmd.Flags().String("out-dir", "", "The directory to export --item-glob into, as <item>/<section>.<format>")
+	exportCmd.MarkFlagsOneRequired("item", "item-glob")
+	exportCmd.MarkFlagsMutuallyExclusive("item", "item-glob")
 
 	exportCmd.Flags().String("section", "", "The section name")
 
 	exportCmd.Flags().String("format", "env", "The file format to save as (env, json, tfvars, hcl)")
 
+	exportCmd.Flags().String("schema", "", "Refuse to export unless the section matches this schema")
+
+	exportCmd.Flags().Bool("watch", false, "Keep polling the section and rewrite the file when it changes")
+	exportCmd.Flags().Duration("interval", 30*time.Second, "How often to poll the section when watching")
+	exportCmd.Flags().String("on-change", "", "A command to run after the file is rewritten")
+	exportCmd.Flags().Int("signal-pid", 0, "A process to signal after the file is rewritten")
+	exportCmd.Flags().String("signal", "HUP", "The signal to send to --signal-pid")
+	exportCmd.MarkFlagsMutuallyExclusive("item-glob", "watch")
+
+	exportCmd.Flags().Bool("offline", false, "Only read from the offline cache, see offline_cache in the config")
 }
@@ -0,0 +1,123 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// genCmd groups the code generators
+var genCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "Generate code from the keys in a section or file",
+}
+
+// genGoCmd generates a typed config struct
+var genGoCmd = &cobra.Command{
+	Use:   "go",
+	Short: "Generate a Go config struct with env tags and a Load function",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		packageName, err := cmd.Flags().GetString("package")
+		if err != nil {
+			return err
+		}
+
+		typeName, err := cmd.Flags().GetString("type")
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		environment, err := readSectionOrFile(cmd)
+		if err != nil {
+			return err
+		}
+
+		out, err := service.GenerateGoConfig(packageName, typeName, environment)
+		if err != nil {
+			return err
+		}
+
+		if output == "" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+
+		return os.WriteFile(output, out, 0o644)
+	},
+}
+
+// readSectionOrFile reads the 1password section when --vault is set, otherwise the local file
+func readSectionOrFile(cmd *cobra.Command) (map[string]any, error) {
+	vaultName, err := cmd.Flags().GetString("vault")
+	if err != nil {
+		return nil, err
+	}
+
+	if vaultName != "" {
+		itemName, err := cmd.Flags().GetString("item")
+		if err != nil {
+			return nil, err
+		}
+
+		sectionName, err := cmd.Flags().GetString("section")
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		return service.ReadOnePassword(cmd.Context(), client, vaultName, itemName, sectionName)
+	}
+
+	envFile, err := cmd.Flags().GetString("env-file")
+	if err != nil {
+		return nil, err
+	}
+
+	envName, err := cmd.Flags().GetString("env-name")
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return nil, err
+	}
+
+	cascade, err := envCascade(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.ReadFormat(format, cascade, envName, envFile)
+}
+
+func init() {
+	rootCmd.AddCommand(genCmd)
+	genCmd.AddCommand(genGoCmd)
+
+	genGoCmd.Flags().String("package", "config", "The package name of the generated file")
+	genGoCmd.Flags().String("type", "Config", "The name of the generated struct")
+	genGoCmd.Flags().StringP("output", "o", "", "The file to write (default is stdout)")
+
+	genGoCmd.Flags().String("vault", "", "Read the keys from a 1password section in this vault")
+	genGoCmd.Flags().String("item", "", "The name of the item")
+	genGoCmd.Flags().String("section", "", "The section to read the keys from")
+
+	genGoCmd.Flags().String("env-file", "", "Read the keys from this file instead")
+	genGoCmd.Flags().String("env-name", "", "The environment, the files read for it depend on --cascade")
+	genGoCmd.Flags().String("cascade", "default", "The order env, json and tfvars files are read in, default, symfony, dotenv-flow, rails or custom:<patterns>")
+	genGoCmd.Flags().String("format", "env", "The input format, env, json or tfvars")
+}
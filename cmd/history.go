@@ -0,0 +1,62 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd lists the local snapshots
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the local snapshots taken before items were changed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		itemName, err := cmd.Flags().GetString("item")
+		if err != nil {
+			return err
+		}
+
+		store, err := snapshotStore()
+		if err != nil {
+			return err
+		}
+
+		snapshots, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SNAPSHOT\tTAKEN\tOPERATION\tITEM\tSECTIONS\tFIELDS")
+		for _, snapshot := range snapshots {
+			if itemName != "" && snapshot.Item.Title != itemName {
+				continue
+			}
+
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%d\t%d\n",
+				snapshot.ID,
+				snapshot.TakenAt.Local().Format(time.DateTime),
+				snapshot.Operation,
+				snapshot.Item.Title,
+				len(snapshot.Item.Sections),
+				len(snapshot.Item.Fields),
+			)
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("item", "", "Only list snapshots of the item with this name")
+}
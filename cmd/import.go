@@ -4,8 +4,11 @@ Copyright © 2025 John Lennard <john@yakmoo.se>
 package cmd
 
 import (
-	"context"
+	"cmp"
 	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"github.com/yakmoose/envop/service"
@@ -41,14 +44,13 @@ var importCmd = &cobra.Command{
 			return err
 		}
 
-		token, err := cmd.Flags().GetString("service-account")
+		discoverDir, err := cmd.Flags().GetString("discover")
 		if err != nil {
 			return err
 		}
 
-		client, err := service.NewClientFromToken(token)
-		if err != nil {
-			return err
+		if discoverDir != "" {
+			return discoverImport(cmd, vaultName, discoverDir)
 		}
 
 		format, err := cmd.Flags().GetString("format")
@@ -56,31 +58,46 @@ var importCmd = &cobra.Command{
 			return err
 		}
 
-		var environment map[string]any
+		cascade, err := envCascade(cmd)
+		if err != nil {
+			return err
+		}
 
-		switch format {
-		case "env":
-			environment, err = service.ReadEnv(envName, envFile)
-		case "hcl", "tfvar", "tfvars":
-			environment, err = service.ReadHcl(envName, envFile)
-		case "json":
-			environment, err = service.ReadJson(envName, envFile)
+		environment, sources, err := service.ReadCascade(format, cascade, envName, envFile)
+		if err != nil {
+			return err
 		}
 
+		explain, err := cmd.Flags().GetBool("explain")
 		if err != nil {
 			return err
 		}
 
+		if explain {
+			printSources(sources)
+			return nil
+		}
+
 		if len(environment) == 0 {
 			return fmt.Errorf("no items found for environment: %s", envName)
 		}
 
-		vault, err := service.FindVaultWithName(client, vaultName)
+		err = validateSchema(cmd, cmp.Or(envName, sectionName), environment)
 		if err != nil {
 			return err
 		}
 
-		item, err := service.FindItemWithName(client, vault, itemName)
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
+		if err != nil {
+			return err
+		}
+
+		item, err := service.FindItemWithName(cmd.Context(), client, vault, itemName)
 		if err != nil {
 			return err
 		}
@@ -90,27 +107,34 @@ var importCmd = &cobra.Command{
 			return err
 		}
 
+		replaced := false
 		if item != nil && replace {
-			err := client.Items().Delete(context.Background(), vault.ID, item.ID)
+			err := client.Items().Delete(cmd.Context(), vault.ID, item.ID)
 			if err != nil {
 				return err
 			}
 			item = nil
+			replaced = true
 		}
 
 		if item == nil {
 			item, err = service.CreateItem(
+				cmd.Context(),
 				client,
 				vault,
 				itemName,
 				sectionName,
 			)
+			if err != nil && replaced {
+				return fmt.Errorf("item %s was deleted but could not be recreated, see envop history: %w", itemName, err)
+			}
 			if err != nil {
 				return err
 			}
 		}
 
-		item, err = service.UpdateItem(
+		updatedItem, err := service.UpdateItem(
+			cmd.Context(),
 			client,
 			item,
 			sectionName,
@@ -118,30 +142,170 @@ var importCmd = &cobra.Command{
 		)
 
 		if err != nil {
-			return err
+			return fmt.Errorf("item %s (%s) exists but the fields could not be saved: %w", item.Title, item.ID, err)
 		}
 
-		fmt.Printf("item created: %s (%s)\n", item.Title, item.ID)
+		fmt.Printf("item created: %s (%s)\n", updatedItem.Title, updatedItem.ID)
 
 		return nil
 	},
 }
 
+// envCascade returns the cascade env files are read with, from --cascade
+func envCascade(cmd *cobra.Command) (service.Cascade, error) {
+	spec, err := cmd.Flags().GetString("cascade")
+	if err != nil {
+		return service.Cascade{}, err
+	}
+	return service.ParseCascade(spec)
+}
+
+// printSources prints which file each key was read from, sorted by key
+func printSources(sources map[string]string) {
+	keys := make([]string, 0, len(sources))
+	for key := range sources {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tFILE")
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", key, sources[key])
+	}
+	w.Flush()
+}
+
+// discoverImport finds the files to import under the directory, shows the plan and imports them once confirmed
+func discoverImport(cmd *cobra.Command, vaultName string, dir string) error {
+	itemPattern, err := cmd.Flags().GetString("item-pattern")
+	if err != nil {
+		return err
+	}
+
+	sectionPattern, err := cmd.Flags().GetString("section-pattern")
+	if err != nil {
+		return err
+	}
+
+	jsonGlob, err := cmd.Flags().GetString("json-glob")
+	if err != nil {
+		return err
+	}
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	schemaPath, err := cmd.Flags().GetString("schema")
+	if err != nil {
+		return err
+	}
+
+	var validate func(envName string, env map[string]any) error
+	if schemaPath != "" {
+		schema, err := service.ReadSchema(schemaPath)
+		if err != nil {
+			return err
+		}
+		validate = schema.Validate
+	}
+
+	cascade, err := envCascade(cmd)
+	if err != nil {
+		return err
+	}
+
+	targets, err := service.DiscoverImports(dir, itemPattern, sectionPattern, jsonGlob)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("no env, json or tfvars files found in %s", dir)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ITEM\tSECTION\tFORMAT\tFILE")
+	for _, target := range targets {
+		file := target.Path
+		if target.Format == "env" && target.EnvName != "" {
+			file += " (" + target.EnvName + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", target.Item, target.Section, target.Format, file)
+	}
+	w.Flush()
+
+	if dryRun {
+		return nil
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Import %d files into vault %s?", len(targets), vaultName)) {
+		return fmt.Errorf("import cancelled")
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
+	if err != nil {
+		return err
+	}
+
+	results := service.DiscoveredImport{Targets: targets, Cascade: cascade, Validate: validate}.Run(cmd.Context(), client, vault)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("failed: %s/%s from %s: %v\n", result.Target.Item, result.Target.Section, result.Target.Path, result.Err)
+			continue
+		}
+		fmt.Printf("imported: %s/%s, %d keys\n", result.Target.Item, result.Target.Section, result.Keys)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be imported", failed, len(results))
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(importCmd)
 	importCmd.Flags().String("env-file", "", "The env file base")
-	importCmd.Flags().String("env-name", "", "The environment, will try <path>, <path>.local, <path>.<env> and <path>.<env>.local")
+	importCmd.Flags().String("env-name", "", "The environment, the files read for it depend on --cascade")
+	importCmd.Flags().String("cascade", "default", "The order env, json and tfvars files are read in, default, symfony, dotenv-flow, rails or custom:<patterns>")
+	importCmd.Flags().Bool("explain", false, "Show which file each key would be imported from, without importing")
 
 	importCmd.Flags().String("vault", "", "The 1password vault")
 	importCmd.MarkFlagRequired("vault")
 
 	importCmd.Flags().String("section", "", "The 1password section to add fields to")
-	importCmd.MarkFlagRequired("section")
-
 	importCmd.Flags().String("item", "", "The name of the item to save")
-	importCmd.MarkFlagRequired("item")
+
+	importCmd.Flags().String("discover", "", "Import every env, json and tfvars file found in this directory")
+	importCmd.Flags().String("item-pattern", "{dir}", "The item to import discovered files into, {dir}, {path} and {env} are replaced")
+	importCmd.Flags().String("section-pattern", "{env}", "The section to import discovered files into, {dir}, {path} and {env} are replaced")
+	importCmd.Flags().String("json-glob", "", "The names of the JSON files to discover, eg '*.json' (default config, settings, secrets and appsettings)")
+	importCmd.Flags().Bool("dry-run", false, "Show which discovered files would be imported where, without importing them")
+	importCmd.Flags().Bool("yes", false, "Import the discovered files without asking")
+
+	importCmd.MarkFlagsOneRequired("item", "discover")
+	importCmd.MarkFlagsOneRequired("section", "discover")
+	importCmd.MarkFlagsMutuallyExclusive("item", "discover")
+	importCmd.MarkFlagsMutuallyExclusive("section", "discover")
 
 	importCmd.Flags().String("format", "env", "The input format, env, json or tfvars")
 
 	importCmd.Flags().Bool("replace", false, "Replace existing item instead of appending to it")
+
+	importCmd.Flags().String("schema", "", "Refuse to import unless the file matches this schema")
 }
@@ -0,0 +1,129 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// matrixCmd shows which keys each environment has
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Show which keys are present, missing or empty in each section",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaultName, err := cmd.Flags().GetString("vault")
+		if err != nil {
+			return err
+		}
+
+		itemNames, err := cmd.Flags().GetStringSlice("item")
+		if err != nil {
+			return err
+		}
+
+		sectionNames, err := cmd.Flags().GetStringSlice("section")
+		if err != nil {
+			return err
+		}
+
+		failOnMissing, err := cmd.Flags().GetBool("fail-on-missing")
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
+		if err != nil {
+			return err
+		}
+
+		items, err := service.FindItemsWithNames(cmd.Context(), client, vault, itemNames)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range items {
+			if item == nil {
+				return fmt.Errorf("Item %s not found in vault %s", itemNames[i], vaultName)
+			}
+		}
+
+		matrix := service.BuildKeyMatrix(items, sectionNames)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		header := []string{"KEY"}
+		for _, column := range matrix.Columns {
+			if len(items) > 1 {
+				header = append(header, column.Item+"/"+column.Section)
+			} else {
+				header = append(header, column.Section)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+
+		for _, key := range matrix.Keys {
+			row := []string{key}
+			for _, cell := range matrix.Cells[key] {
+				switch cell.State {
+				case "missing":
+					row = append(row, "-")
+				case "empty":
+					row = append(row, "empty")
+				default:
+					row = append(row, valueGroup(cell.Group))
+				}
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Println("cells with the same letter in a row have the same value, - is missing")
+
+		missing := matrix.Missing()
+		if failOnMissing && missing > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d values are missing", missing)
+		}
+
+		return nil
+	},
+}
+
+// valueGroup names a group of equal values A, B, ... Z, AA, AB
+func valueGroup(group int) string {
+	name := ""
+	for group > 0 {
+		group--
+		name = string(rune('A'+group%26)) + name
+		group /= 26
+	}
+	return name
+}
+
+func init() {
+	rootCmd.AddCommand(matrixCmd)
+
+	matrixCmd.Flags().String("vault", "", "The 1password vault")
+	matrixCmd.MarkFlagRequired("vault")
+
+	matrixCmd.Flags().StringSlice("item", nil, "The names of the items to compare")
+	matrixCmd.MarkFlagRequired("item")
+
+	matrixCmd.Flags().StringSlice("section", nil, "The sections to compare (default is every section)")
+	matrixCmd.Flags().Bool("fail-on-missing", false, "Exit with an error when any key is missing from a section, for CI")
+}
@@ -4,6 +4,7 @@ Copyright © 2025 John Lennard <john@yakmoo.se>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -46,22 +47,17 @@ var moveCmd = &cobra.Command{
 			destinationSectionName = sourceSectionName
 		}
 
-		token, err := cmd.Flags().GetString("service-account")
+		client, err := newClient(cmd)
 		if err != nil {
 			return err
 		}
 
-		client, err := service.NewClientFromToken(token)
+		sourceVault, err := service.FindVaultWithName(cmd.Context(), client, sourceVaultName)
 		if err != nil {
 			return err
 		}
 
-		sourceVault, err := service.FindVaultWithName(client, sourceVaultName)
-		if err != nil {
-			return err
-		}
-
-		sourceItem, err := service.FindItemWithName(client, sourceVault, sourceItemName)
+		sourceItem, err := service.FindItemWithName(cmd.Context(), client, sourceVault, sourceItemName)
 		if err != nil {
 			return err
 		}
@@ -70,23 +66,43 @@ var moveCmd = &cobra.Command{
 			return fmt.Errorf("Item %s not found in vault %s", sourceItemName, sourceVaultName)
 		}
 
-		destinationVault, err := service.FindVaultWithName(client, destinationVaultName)
+		destinationVault, err := service.FindVaultWithName(cmd.Context(), client, destinationVaultName)
 		if err != nil {
 			return err
 		}
 
-		destinationItem, err := service.FindItemWithName(client, destinationVault, destinationItemName)
+		destinationItem, err := service.FindItemWithName(cmd.Context(), client, destinationVault, destinationItemName)
 		if err != nil {
 			return err
 		}
 
-		return service.MoveSection(
+		created := destinationItem == nil
+		if created {
+			destinationItem, err = service.CreateItem(cmd.Context(), client, destinationVault, destinationItemName, destinationSectionName)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = service.MoveSection(
+			cmd.Context(),
 			client,
 			sourceItem,
 			sourceSectionName,
 			destinationItem,
 			destinationSectionName,
 		)
+
+		// don't leave behind the empty item that was created for a move that didn't happen
+		var moveError *service.MoveError
+		if created && errors.As(err, &moveError) && moveError.Unchanged {
+			deleteErr := client.Items().Delete(cmd.Context(), destinationItem.VaultID, destinationItem.ID)
+			if deleteErr != nil {
+				moveError.Step += fmt.Sprintf(", and the new item %s could not be deleted (%v)", destinationItem.Title, deleteErr)
+			}
+		}
+
+		return err
 	},
 }
 
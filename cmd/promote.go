@@ -0,0 +1,199 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/yakmoose/envop/service"
+)
+
+// promoteCmd promotes keys from one environment section to another
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Promote keys from one environment section to another, eg staging to production",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fromSectionName, err := cmd.Flags().GetString("from")
+		if err != nil {
+			return err
+		}
+
+		toSectionName, err := cmd.Flags().GetString("to")
+		if err != nil {
+			return err
+		}
+
+		vaultName, _ := cmd.Flags().GetString("vault")
+		itemName, _ := cmd.Flags().GetString("item")
+
+		fromVaultName, _ := cmd.Flags().GetString("from-vault")
+		if fromVaultName == "" {
+			fromVaultName = vaultName
+		}
+
+		fromItemName, _ := cmd.Flags().GetString("from-item")
+		if fromItemName == "" {
+			fromItemName = itemName
+		}
+
+		toVaultName, _ := cmd.Flags().GetString("to-vault")
+		if toVaultName == "" {
+			toVaultName = fromVaultName
+		}
+
+		toItemName, _ := cmd.Flags().GetString("to-item")
+		if toItemName == "" {
+			toItemName = fromItemName
+		}
+
+		if fromVaultName == "" || fromItemName == "" {
+			return fmt.Errorf("set --vault and --item, or --from-vault and --from-item")
+		}
+
+		keys, err := cmd.Flags().GetStringSlice("keys")
+		if err != nil {
+			return err
+		}
+
+		interactive, err := cmd.Flags().GetBool("interactive")
+		if err != nil {
+			return err
+		}
+
+		envSpecific, err := cmd.Flags().GetStringSlice("env-specific")
+		if err != nil {
+			return err
+		}
+		envSpecific = append(envSpecific, viper.GetStringSlice("env_specific")...)
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		fromVault, err := service.FindVaultWithName(cmd.Context(), client, fromVaultName)
+		if err != nil {
+			return err
+		}
+
+		fromItem, err := service.FindItemWithName(cmd.Context(), client, fromVault, fromItemName)
+		if err != nil {
+			return err
+		}
+
+		if fromItem == nil {
+			return fmt.Errorf("Item %s not found in vault %s", fromItemName, fromVaultName)
+		}
+
+		toVault, err := service.FindVaultWithName(cmd.Context(), client, toVaultName)
+		if err != nil {
+			return err
+		}
+
+		toItem, err := service.FindItemWithName(cmd.Context(), client, toVault, toItemName)
+		if err != nil {
+			return err
+		}
+
+		// an item that doesn't exist yet is diffed as empty, and only created once the promote is confirmed
+		changes, err := service.DiffSections(fromItem, fromSectionName, cmp.Or(toItem, &onepassword.Item{}), toSectionName)
+		if err != nil {
+			return err
+		}
+
+		promote := make([]string, 0, len(changes))
+		for _, change := range changes {
+			switch {
+			case change.Kind == "removed":
+				fmt.Printf("  %s (only in %s, kept)\n", change.Title, toSectionName)
+			case slices.Contains(envSpecific, change.Title):
+				fmt.Printf("  %s (environment specific, skipped)\n", change.Title)
+			case len(keys) > 0 && !slices.Contains(keys, change.Title):
+				fmt.Printf("  %s (%s, not selected)\n", change.Title, change.Kind)
+			case interactive && !confirm(fmt.Sprintf("promote %s (%s)?", change.Title, change.Kind)):
+				fmt.Printf("  %s (%s, not selected)\n", change.Title, change.Kind)
+			default:
+				promote = append(promote, change.Title)
+				if change.Kind == "added" {
+					fmt.Printf("+ %s\n", change.Title)
+				} else {
+					fmt.Printf("~ %s\n", change.Title)
+				}
+			}
+		}
+
+		for _, key := range keys {
+			if !slices.ContainsFunc(changes, func(change service.FieldChange) bool { return change.Title == key }) {
+				fmt.Printf("  %s (already the same in %s)\n", key, toSectionName)
+			}
+		}
+
+		if len(promote) == 0 {
+			fmt.Printf("nothing to promote from %s to %s\n", fromSectionName, toSectionName)
+			return nil
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		if !yes && !interactive && !confirm(fmt.Sprintf("promote %d keys from %s to %s?", len(promote), fromSectionName, toSectionName)) {
+			return fmt.Errorf("promote cancelled")
+		}
+
+		if toItem == nil {
+			toItem, err = service.CreateItem(cmd.Context(), client, toVault, toItemName, toSectionName)
+			if err != nil {
+				return err
+			}
+		}
+
+		item, err := service.PromoteKeys(cmd.Context(), client, fromItem, fromSectionName, toItem, toSectionName, promote)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("promoted %d keys to %s in %s (%s)\n", len(promote), toSectionName, item.Title, item.ID)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().String("from", "", "The 1password section to promote from, eg staging")
+	promoteCmd.MarkFlagRequired("from")
+
+	promoteCmd.Flags().String("to", "", "The 1password section to promote to, eg production")
+	promoteCmd.MarkFlagRequired("to")
+
+	promoteCmd.Flags().String("vault", "", "The 1password vault")
+	promoteCmd.Flags().String("item", "", "The name of the item")
+	promoteCmd.Flags().String("from-vault", "", "The 1password vault to promote from (default is --vault)")
+	promoteCmd.Flags().String("from-item", "", "The name of the item to promote from (default is --item)")
+	promoteCmd.Flags().String("to-vault", "", "The 1password vault to promote to (default is the from vault)")
+	promoteCmd.Flags().String("to-item", "", "The name of the item to promote to (default is the from item)")
+
+	promoteCmd.Flags().StringSlice("keys", nil, "Only promote these keys (default is all the keys that differ)")
+	promoteCmd.Flags().Bool("interactive", false, "Ask before promoting each key")
+	promoteCmd.Flags().StringSlice("env-specific", nil, "Keys that are specific to an environment and never promoted, added to env_specific in the config")
+	promoteCmd.Flags().Bool("dry-run", false, "Show the differences without promoting anything")
+	promoteCmd.Flags().Bool("yes", false, "Promote without asking for confirmation")
+}
@@ -25,22 +25,17 @@ var reindexCmd = &cobra.Command{
 			return err
 		}
 
-		token, err := cmd.Flags().GetString("service-account")
+		client, err := newClient(cmd)
 		if err != nil {
 			return err
 		}
 
-		client, err := service.NewClientFromToken(token)
+		vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
 		if err != nil {
 			return err
 		}
 
-		vault, err := service.FindVaultWithName(client, vaultName)
-		if err != nil {
-			return err
-		}
-
-		item, err := service.FindItemWithName(client, vault, itemName)
+		item, err := service.FindItemWithName(cmd.Context(), client, vault, itemName)
 		if err != nil {
 			return err
 		}
@@ -49,7 +44,7 @@ var reindexCmd = &cobra.Command{
 			return fmt.Errorf("Item %s not found in vault %s", itemName, vaultName)
 		}
 
-		_, err = service.ReindexItem(client, item)
+		_, err = service.ReindexItem(cmd.Context(), client, item)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// restoreCmd recreates the items in an encrypted archive
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the items in an encrypted archive into a vault",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, err := cmd.Flags().GetString("input")
+		if err != nil {
+			return err
+		}
+
+		vaultName, err := cmd.Flags().GetString("vault")
+		if err != nil {
+			return err
+		}
+
+		skipExisting, err := cmd.Flags().GetBool("skip-existing")
+		if err != nil {
+			return err
+		}
+
+		identities, err := backupIdentities(cmd)
+		if err != nil {
+			return err
+		}
+
+		fh, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+
+		backup, err := service.ReadBackup(fh, identities...)
+		if err != nil {
+			return err
+		}
+
+		if vaultName == "" {
+			vaultName = backup.Vault
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		vault, err := service.FindVaultWithName(cmd.Context(), client, vaultName)
+		if err != nil {
+			return err
+		}
+
+		report, err := service.RestoreBackup(cmd.Context(), client, backup, vault, skipExisting)
+		if report != nil {
+			for _, title := range report.Created {
+				fmt.Printf("item restored: %s\n", title)
+			}
+			for _, title := range report.Skipped {
+				fmt.Printf("item skipped: %s\n", title)
+			}
+		}
+
+		return err
+	},
+}
+
+// backupIdentities returns the age identities from --identity, or a passphrase identity when there are none
+func backupIdentities(cmd *cobra.Command) ([]age.Identity, error) {
+	identityFile, err := cmd.Flags().GetString("identity")
+	if err != nil {
+		return nil, err
+	}
+
+	if identityFile != "" {
+		raw, err := service.ReadPrivateFile(identityFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return age.ParseIdentities(strings.NewReader(raw))
+	}
+
+	passphrase, err := backupPassphrase(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("set --identity, --passphrase-file or ENVOP_BACKUP_PASSPHRASE to decrypt the backup")
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return []age.Identity{identity}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().StringP("input", "i", "", "The archive to restore from")
+	restoreCmd.MarkFlagRequired("input")
+
+	restoreCmd.Flags().String("vault", "", "The 1password vault to restore into (default is the vault that was backed up)")
+	restoreCmd.Flags().Bool("skip-existing", false, "Skip items with the same name as an item in the vault")
+
+	restoreCmd.Flags().String("identity", "", "The age identity file to decrypt with")
+	restoreCmd.Flags().String("passphrase-file", "", "Decrypt with the passphrase in this file instead of an age identity")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// rollbackCmd restores an item from a local snapshot
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore the sections and fields of an item from a local snapshot",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshotID, err := cmd.Flags().GetString("to")
+		if err != nil {
+			return err
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+
+		store, err := snapshotStore()
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := store.Load(snapshotID)
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		current, err := service.CurrentItemForSnapshot(cmd.Context(), client, snapshot)
+		if err != nil {
+			return err
+		}
+
+		if current == nil {
+			fmt.Printf("item %s no longer exists and will be recreated\n", snapshot.Item.Title)
+			current = &onepassword.Item{}
+		}
+
+		changes := service.DiffItems(current, &snapshot.Item)
+		if len(changes) == 0 {
+			fmt.Printf("item %s already matches snapshot %s\n", snapshot.Item.Title, snapshot.ID)
+			return nil
+		}
+
+		printChanges(changes)
+
+		if !yes && !confirm(fmt.Sprintf("restore %s from %s?", snapshot.Item.Title, snapshot.ID)) {
+			return fmt.Errorf("rollback cancelled")
+		}
+
+		item, err := service.RestoreSnapshot(cmd.Context(), client, snapshot)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("item restored: %s (%s)\n", item.Title, item.ID)
+
+		return nil
+	},
+}
+
+// printChanges prints the field changes, without the values
+func printChanges(changes []service.FieldChange) {
+	for _, change := range changes {
+		marker := "~"
+		switch change.Kind {
+		case "added":
+			marker = "+"
+		case "removed":
+			marker = "-"
+		}
+
+		if change.Section == "" {
+			fmt.Printf("%s %s\n", marker, change.Title)
+		} else {
+			fmt.Printf("%s %s.%s\n", marker, change.Section, change.Title)
+		}
+	}
+}
+
+// stdin is shared by the prompts, so buffered answers aren't lost between questions
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on stdin
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := stdin.ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().String("to", "", "The snapshot to restore, see envop history")
+	rollbackCmd.MarkFlagRequired("to")
+
+	rollbackCmd.Flags().Bool("yes", false, "Restore without asking for confirmation")
+}
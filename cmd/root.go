@@ -4,26 +4,62 @@ Copyright © 2025 John Lennard <john@yakmoo.se>
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/spf13/pflag"
+	"io/fs"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/1password/onepassword-sdk-go"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/yakmoose/envop/service"
 )
 
 var cfgFile string
 
+// cancelTimeout releases the --timeout context once the command has finished
+var cancelTimeout context.CancelFunc = func() {}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "envop",
 	Short: "Imports environment files into 1password",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The command context is cancelled on SIGINT and SIGTERM, so operations can stop cleanly.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+
+	var code exitCode
+	if errors.As(err, &code) {
+		os.Exit(int(code))
+	}
+
 	if err != nil {
 		os.Exit(1)
 	}
@@ -33,10 +69,19 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.envop.json)")
-	rootCmd.PersistentFlags().StringP("service-account", "", "", "1password service account")
-	rootCmd.MarkPersistentFlagRequired("service-account")
+	rootCmd.PersistentFlags().StringP("service-account", "", "", "1password service account token, prefer token_command, token_file or token_keyring in the config file")
+	rootCmd.PersistentFlags().Duration("expiry-warning", time.Hour, "Warn when the service account token expires within this window")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Give up and stop after this long, eg 5m")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Log retries and other details to stderr")
+	rootCmd.PersistentFlags().Int("retry-attempts", 5, "How many times to try a 1password api call that was rate limited or failed transiently")
+	rootCmd.PersistentFlags().Duration("retry-budget", time.Minute, "How long a single 1password api call may spend waiting between retries")
+	rootCmd.PersistentFlags().Duration("lock-timeout", 0, "Keep re-applying changes to items that were changed by someone else for this long, instead of failing")
+	rootCmd.PersistentFlags().Int("workers", 8, "How many items to fetch from 1password at the same time")
+	rootCmd.PersistentFlags().String("agent-socket", service.DefaultAgentSocket(), "The socket of the envop agent")
+	rootCmd.PersistentFlags().Bool("no-agent", false, "Talk to 1password directly even when the envop agent is running")
 
 	viper.BindPFlag("service-account", rootCmd.PersistentFlags().Lookup("service-account"))
+	viper.SetDefault("snapshots", true)
 
 }
 
@@ -72,3 +117,298 @@ func initConfig() {
 		}
 	})
 }
+
+// newClient creates a 1password client, going through the agent when one is running. Otherwise the
+// client is created from the service account flag, falling back to the token_command, token_file and
+// token_keyring config options. The offline cache is keyed by the token, so they run with the agent too
+// when it is used, but never more than once a command.
+func newClient(cmd *cobra.Command) (*onepassword.Client, error) {
+	var err error
+	service.LockTimeout, err = cmd.Flags().GetDuration("lock-timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	service.Workers, err = cmd.Flags().GetInt("workers")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := agentClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client, err = newServiceClient(cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	client = service.WithLookupCache(client)
+
+	if viper.GetBool("snapshots") {
+		store, err := snapshotStore()
+		if err != nil {
+			return nil, err
+		}
+		client = service.WithSnapshots(client, store)
+	}
+
+	return client, nil
+}
+
+// newReadClient is newClient for commands that only read. When offline_cache is set, what is read is saved
+// to the offline cache, and served from it when 1password can't be reached. --offline only uses the cache.
+func newReadClient(cmd *cobra.Command) (*onepassword.Client, error) {
+	offline, err := cmd.Flags().GetBool("offline")
+	if err != nil {
+		return nil, err
+	}
+
+	if !offline && !viper.GetBool("offline_cache") {
+		return newClient(cmd)
+	}
+
+	cache, err := offlineCache(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if offline {
+		return service.OfflineClient(cache), nil
+	}
+
+	client, err := newClient(cmd)
+	if err != nil && service.IsUnreachable(err) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: could not reach 1password: %v\n", err)
+		return service.OfflineClient(cache), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return service.WithOfflineCache(client, cache), nil
+}
+
+// offlineCache opens the offline cache in offline_cache_dir, it warns with the age of the cache when it is used
+func offlineCache(cmd *cobra.Command) (*service.OfflineCache, error) {
+	dir := viper.GetString("offline_cache_dir")
+	if dir == "" {
+		var err error
+		dir, err = service.DefaultOfflineCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := resolveToken(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := service.NewOfflineCache(dir, token)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Warn = func(savedAt time.Time) {
+		fmt.Fprintf(
+			cmd.ErrOrStderr(),
+			"warning: using the offline cache, last saved %s ago (%s)\n",
+			time.Since(savedAt).Round(time.Second),
+			savedAt.Format(time.RFC3339),
+		)
+	}
+	cache.SaveFailed = func(err error) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+	}
+
+	return cache, nil
+}
+
+// newServiceClient creates a client that talks to 1password directly, retrying rate limited calls
+func newServiceClient(cmd *cobra.Command) (*onepassword.Client, error) {
+	token, err := cmd.Flags().GetString("service-account")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := service.NewClientFromToken(cmd.Context(), token, configToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// the config helpers remember the token, so this doesn't run them again
+	token, err = resolveToken(cmd)
+	if err != nil {
+		return nil, err
+	}
+	warnTokenExpiry(cmd, token)
+
+	policy, err := retryPolicy(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.WithRetry(client, policy), nil
+}
+
+// agentClient connects to the agent on --agent-socket, it returns nil when no agent is running or --no-agent is set.
+// A socket that could belong to someone else is reported and not used.
+func agentClient(cmd *cobra.Command) (*onepassword.Client, error) {
+	noAgent, err := cmd.Flags().GetBool("no-agent")
+	if err != nil || noAgent {
+		return nil, err
+	}
+
+	socketPath, err := cmd.Flags().GetString("agent-socket")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := service.DialAgent(socketPath)
+	if errors.Is(err, service.ErrNoAgentSocket) || errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "not using the envop agent: %s\n", err)
+		return nil, nil
+	}
+
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err == nil && verbose {
+		fmt.Fprintf(cmd.ErrOrStderr(), "using the envop agent on %s\n", socketPath)
+	}
+
+	return client, nil
+}
+
+// retryPolicy builds the retry policy from the retry flags, logging the retries when verbose
+func retryPolicy(cmd *cobra.Command) (service.RetryPolicy, error) {
+	policy := service.DefaultRetryPolicy()
+
+	var err error
+	policy.MaxAttempts, err = cmd.Flags().GetInt("retry-attempts")
+	if err != nil {
+		return policy, err
+	}
+
+	policy.Budget, err = cmd.Flags().GetDuration("retry-budget")
+	if err != nil {
+		return policy, err
+	}
+
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return policy, err
+	}
+
+	if verbose {
+		policy.Log = func(format string, args ...any) {
+			fmt.Fprintf(cmd.ErrOrStderr(), format+"\n", args...)
+		}
+	}
+
+	return policy, nil
+}
+
+// snapshotStore returns the store for the snapshots taken before each write, in snapshot_dir if it is set,
+// and with the key in snapshot_identity if it is set
+func snapshotStore() (*service.SnapshotStore, error) {
+	var err error
+	dir := viper.GetString("snapshot_dir")
+	if dir == "" {
+		dir, err = service.DefaultSnapshotDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identityFile := viper.GetString("snapshot_identity")
+	if identityFile == "" {
+		identityFile, err = service.DefaultSnapshotIdentity()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &service.SnapshotStore{Dir: dir, IdentityFile: identityFile}, nil
+}
+
+// resolvedToken is the token once the config helpers have resolved it, so a token_command that prompts, eg for
+// a fingerprint, only runs once however many clients and caches a command needs
+var resolvedToken string
+
+// configToken is the token helper for the token_command, token_file and token_keyring config options
+func configToken(ctx context.Context) (string, error) {
+	if resolvedToken != "" {
+		return resolvedToken, nil
+	}
+
+	token, err := service.ResolveToken(
+		ctx,
+		"",
+		service.TokenFromCommand(viper.GetString("token_command")),
+		service.TokenFromFile(viper.GetString("token_file")),
+		service.TokenFromKeyring(viper.GetString("token_keyring"), viper.GetString("token_keyring_account")),
+	)
+	if errors.Is(err, service.ErrNoToken) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resolvedToken = token
+	return token, nil
+}
+
+// resolveToken resolves the service account token from the flag or the config helpers
+func resolveToken(cmd *cobra.Command) (string, error) {
+	token, err := cmd.Flags().GetString("service-account")
+	if err != nil {
+		return "", err
+	}
+
+	return service.ResolveToken(cmd.Context(), token, configToken)
+}
+
+// tokenInfo decodes the token, using token_expires_at from the config when the token has no expiry
+func tokenInfo(token string) (*service.TokenInfo, error) {
+	info, err := service.DecodeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.ExpiresAt == nil && viper.IsSet("token_expires_at") {
+		expiresAt, err := time.Parse(time.RFC3339, viper.GetString("token_expires_at"))
+		if err != nil {
+			return nil, fmt.Errorf("token_expires_at must be an RFC3339 time: %w", err)
+		}
+		info.ExpiresAt = &expiresAt
+	}
+
+	return info, nil
+}
+
+// warnTokenExpiry prints a warning to stderr when the token expires within the expiry-warning window
+func warnTokenExpiry(cmd *cobra.Command, token string) {
+	window, err := cmd.Flags().GetDuration("expiry-warning")
+	if err != nil || window <= 0 {
+		return
+	}
+
+	info, err := tokenInfo(token)
+	if err != nil || !info.ExpiresWithin(window) {
+		return
+	}
+
+	remaining := time.Until(*info.ExpiresAt).Round(time.Second)
+	if remaining <= 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: service account token expired at %s\n", info.ExpiresAt.Format(time.RFC3339))
+		return
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "warning: service account token expires in %s (%s)\n", remaining, info.ExpiresAt.Format(time.RFC3339))
+}
@@ -0,0 +1,225 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [flags] command [args...]",
+	Short: "Run a command with the section in its environment",
+	Long: `Run a command with the fields of the sections added to its environment. 
+With --restart-on-change or --reload-signal the sections are watched, and the command is restarted 
+or signalled when they change. envop exits with the exit code of the command.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaultName, err := cmd.Flags().GetString("vault")
+		if err != nil {
+			return err
+		}
+
+		itemName, err := cmd.Flags().GetString("item")
+		if err != nil {
+			return err
+		}
+
+		sectionNames, err := cmd.Flags().GetStringSlice("section")
+		if err != nil {
+			return err
+		}
+
+		restartOnChange, err := cmd.Flags().GetBool("restart-on-change")
+		if err != nil {
+			return err
+		}
+
+		reloadSignalName, err := cmd.Flags().GetString("reload-signal")
+		if err != nil {
+			return err
+		}
+
+		reloadEnvFile, err := cmd.Flags().GetString("reload-env-file")
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			return err
+		}
+
+		if interval <= 0 && (restartOnChange || reloadSignalName != "") {
+			return fmt.Errorf("--interval must be more than 0, got %s", interval)
+		}
+
+		gracePeriod, err := cmd.Flags().GetDuration("grace-period")
+		if err != nil {
+			return err
+		}
+
+		stopSignalName, err := cmd.Flags().GetString("stop-signal")
+		if err != nil {
+			return err
+		}
+
+		if restartOnChange && reloadSignalName != "" {
+			return fmt.Errorf("use either --restart-on-change or --reload-signal, not both")
+		}
+
+		stopSignal, err := service.ParseSignal(stopSignalName)
+		if err != nil {
+			return err
+		}
+
+		client, err := newReadClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		env, err := service.ReadSections(cmd.Context(), client, vaultName, itemName, sectionNames...)
+		if err != nil {
+			return err
+		}
+
+		if reloadEnvFile != "" {
+			err = service.WriteAtomic(reloadEnvFile, format, env)
+			if err != nil {
+				return err
+			}
+		}
+
+		process := &service.Process{Command: args, StopSignal: stopSignal, GracePeriod: gracePeriod}
+		err = process.Start(env)
+		if err != nil {
+			return err
+		}
+
+		changes := make(chan map[string]any)
+		if restartOnChange || reloadSignalName != "" {
+			watcher := service.Watch{
+				Vault:    vaultName,
+				Item:     itemName,
+				Sections: sectionNames,
+				Interval: interval,
+				Failed: func(err error) {
+					fmt.Fprintf(os.Stderr, "could not read %s: %v\n", itemName, err)
+				},
+			}
+
+			go watcher.Run(cmd.Context(), client, env, func(env map[string]any) error {
+				select {
+				case changes <- env:
+				case <-cmd.Context().Done():
+				}
+				return nil
+			})
+		}
+
+		for {
+			select {
+			case err := <-process.Exited():
+				return exitWith(cmd, err)
+
+			case <-cmd.Context().Done():
+				return exitWith(cmd, process.Stop())
+
+			case env := <-changes:
+				if restartOnChange {
+					fmt.Fprintf(os.Stderr, "%s changed, restarting %s\n", itemName, args[0])
+					err = process.Restart(env)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+
+				err = reloadProcess(process, reloadSignalName, reloadEnvFile, format, env)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "could not reload %s: %v\n", args[0], err)
+				}
+			}
+		}
+	},
+}
+
+// reloadProcess writes the new environment to the env file and sends the reload signal
+func reloadProcess(process *service.Process, signalName string, envFile string, format string, env map[string]any) error {
+	signal, err := service.ParseSignal(signalName)
+	if err != nil {
+		return err
+	}
+
+	if envFile != "" {
+		err = service.WriteAtomic(envFile, format, env)
+		if err != nil {
+			return err
+		}
+	}
+
+	return process.Signal(signal)
+}
+
+// exitCode is returned by a command to make envop exit with the code, without printing an error
+type exitCode int
+
+func (e exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// exitWith returns the exit code of the command for Execute to exit with, or the error if it didn't run.
+// A command killed by a signal exits with 128 plus the signal, like a shell.
+func exitWith(cmd *cobra.Command, err error) error {
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return err
+	}
+
+	code := exitError.ExitCode()
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		code = 128 + int(status.Signal())
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return exitCode(code)
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().SetInterspersed(false)
+
+	runCmd.Flags().String("vault", "", "The 1password vault")
+	runCmd.MarkFlagRequired("vault")
+
+	runCmd.Flags().String("item", "", "The name of the item")
+	runCmd.MarkFlagRequired("item")
+
+	runCmd.Flags().StringSlice("section", []string{""}, "The sections to read, keys in later sections replace earlier ones")
+
+	runCmd.Flags().Bool("restart-on-change", false, "Restart the command when the sections change")
+	runCmd.Flags().String("reload-signal", "", "Signal the command when the sections change, eg HUP")
+	runCmd.Flags().String("reload-env-file", "", "Keep this file up to date for the command to read when it is signalled")
+	runCmd.Flags().String("format", "env", "The format of --reload-env-file (env, json, tfvars, hcl)")
+
+	runCmd.Flags().Duration("interval", 30*time.Second, "How often to poll the sections for changes")
+	runCmd.Flags().String("stop-signal", "TERM", "The signal sent to stop the command")
+	runCmd.Flags().Duration("grace-period", 10*time.Second, "How long to wait after the stop signal before killing the command")
+
+	runCmd.Flags().Bool("offline", false, "Only read from the offline cache, see offline_cache in the config")
+}
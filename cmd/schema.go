@@ -0,0 +1,91 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// schemaCmd generates a JSON Schema from a section
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Generate a JSON Schema describing the keys in a 1password section",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaultName, err := cmd.Flags().GetString("vault")
+		if err != nil {
+			return err
+		}
+
+		itemName, err := cmd.Flags().GetString("item")
+		if err != nil {
+			return err
+		}
+
+		sectionName, err := cmd.Flags().GetString("section")
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+
+		merge, err := cmd.Flags().GetString("merge")
+		if err != nil {
+			return err
+		}
+
+		if merge == "" {
+			merge = output
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		env, err := service.ReadOnePassword(cmd.Context(), client, vaultName, itemName, sectionName)
+		if err != nil {
+			return err
+		}
+
+		schema := service.GenerateJSONSchema(env)
+
+		if merge != "" {
+			existing, err := service.ReadJSONSchema(merge)
+			if err != nil {
+				return err
+			}
+
+			if existing != nil {
+				schema, err = service.MergeJSONSchema(existing, schema)
+				if err != nil {
+					return fmt.Errorf("could not merge with %s: %w", merge, err)
+				}
+			}
+		}
+
+		return service.WriteJSONSchema(output, schema)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().String("vault", "", "The 1password vault")
+	schemaCmd.MarkFlagRequired("vault")
+
+	schemaCmd.Flags().String("item", "", "The name of the item")
+	schemaCmd.MarkFlagRequired("item")
+
+	schemaCmd.Flags().String("section", "", "The section to describe")
+	schemaCmd.MarkFlagRequired("section")
+
+	schemaCmd.Flags().StringP("output", "o", "", "The file to write the schema to, merging with it if it exists (default is stdout)")
+	schemaCmd.Flags().String("merge", "", "An existing schema to merge with (default is --output)")
+}
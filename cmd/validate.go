@@ -0,0 +1,76 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yakmoose/envop/service"
+)
+
+// validateCmd checks a local file or a 1password section against a schema
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a local file or a 1password section against a schema",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envName, err := cmd.Flags().GetString("env-name")
+		if err != nil {
+			return err
+		}
+
+		sectionName, err := cmd.Flags().GetString("section")
+		if err != nil {
+			return err
+		}
+
+		environment, err := readSectionOrFile(cmd)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		err = validateSchema(cmd, cmp.Or(envName, sectionName), environment)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d keys are valid\n", len(environment))
+
+		return nil
+	},
+}
+
+// validateSchema checks the environment against the --schema flag, if it is set
+func validateSchema(cmd *cobra.Command, envName string, environment map[string]any) error {
+	path, err := cmd.Flags().GetString("schema")
+	if err != nil || path == "" {
+		return err
+	}
+
+	schema, err := service.ReadSchema(path)
+	if err != nil {
+		return err
+	}
+
+	return schema.Validate(envName, environment)
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().String("schema", "", "The schema file")
+	validateCmd.MarkFlagRequired("schema")
+
+	validateCmd.Flags().String("env-file", "", "The env file base")
+	validateCmd.Flags().String("env-name", "", "The environment, used to read the file and for required_in in the schema")
+	validateCmd.Flags().String("cascade", "default", "The order env, json and tfvars files are read in, default, symfony, dotenv-flow, rails or custom:<patterns>")
+	validateCmd.Flags().String("format", "env", "The input format, env, json or tfvars")
+
+	validateCmd.Flags().String("vault", "", "Validate a 1password section in this vault instead of a file")
+	validateCmd.Flags().String("item", "", "The name of the item to validate")
+	validateCmd.Flags().String("section", "", "The section to validate, also the environment for required_in")
+}
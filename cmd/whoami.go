@@ -0,0 +1,73 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// whoamiCmd shows what can be derived about the service account token
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the service account, token expiry and accessible vaults",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := resolveToken(cmd)
+		if err != nil {
+			return err
+		}
+
+		info, err := tokenInfo(token)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+
+		fmt.Fprintf(out, "account:         %s\n", info.SignInAddress)
+		fmt.Fprintf(out, "service account: %s\n", info.Email)
+		fmt.Fprintf(out, "device:          %s\n", info.DeviceUUID)
+
+		if info.ExpiresAt == nil {
+			fmt.Fprintln(out, "expires:         unknown (set token_expires_at in the config)")
+		} else {
+			fmt.Fprintf(out, "expires:         %s (in %s)\n", info.ExpiresAt.Format(time.RFC3339), time.Until(*info.ExpiresAt).Round(time.Second))
+		}
+
+		local, err := cmd.Flags().GetBool("local")
+		if err != nil {
+			return err
+		}
+
+		if local {
+			return nil
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		// the token does not carry its vault grants, so the best we can do is list what it can see
+		vaults, err := client.Vaults().List(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, "vaults:")
+		for _, vault := range vaults {
+			fmt.Fprintf(out, "  %s (%s)\n", vault.Title, vault.ID)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+
+	whoamiCmd.Flags().Bool("local", false, "Only decode the token locally, do not list the vaults")
+}
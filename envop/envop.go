@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+
+// Package envop loads secrets from 1password sections at startup, the same way envop export does,
+// without shelling out to the cli.
+//
+//	err := envop.Setenv(ctx, envop.MustParseRef("op://DeploymentSecrets/my-service/production"), envop.Options{})
+package envop
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/yakmoose/envop/service"
+)
+
+// Ref points at one or more sections of an item. Keys in later sections replace the same keys in
+// earlier ones, so a shared section can be followed by an environment specific one.
+type Ref struct {
+	Vault    string
+	Item     string
+	Sections []string
+}
+
+// ParseRef parses a reference like op://vault/item/section, the section can be a comma separated
+// list of sections to merge, eg op://vault/item/shared,production
+func ParseRef(ref string) (Ref, error) {
+	path, ok := strings.CutPrefix(ref, "op://")
+	if !ok {
+		return Ref{}, fmt.Errorf("reference %s must start with op://", ref)
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return Ref{}, fmt.Errorf("reference %s must look like op://vault/item/section", ref)
+	}
+
+	return Ref{
+		Vault:    parts[0],
+		Item:     parts[1],
+		Sections: strings.Split(parts[2], ","),
+	}, nil
+}
+
+// MustParseRef is like ParseRef but panics if the reference is invalid
+func MustParseRef(ref string) Ref {
+	parsed, err := ParseRef(ref)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func (r Ref) String() string {
+	return "op://" + r.Vault + "/" + r.Item + "/" + strings.Join(r.Sections, ",")
+}
+
+// Options controls how the 1password client is created
+type Options struct {
+	// Token is the service account token, the default is OP_SERVICE_ACCOUNT_TOKEN
+	Token string
+	// TokenCommand is run to get the token when there is no Token, as token_command in the cli config
+	TokenCommand string
+	// TokenFile is read to get the token when there is no Token, as token_file in the cli config
+	TokenFile string
+	// Retry controls how rate limited and failed calls are retried, the default is service.DefaultRetryPolicy
+	Retry *service.RetryPolicy
+	// Client is used instead of creating a client from the token, eg service.NewMemoryClient in tests
+	Client *onepassword.Client
+	// AgentSocket is where to look for a running envop agent, the default is service.DefaultAgentSocket
+	AgentSocket string
+	// NoAgent creates a client from the token even when an agent is running
+	NoAgent bool
+}
+
+// client returns the client from the options, the running agent, or creates one from the token
+func (o Options) client(ctx context.Context) (*onepassword.Client, error) {
+	if o.Client != nil {
+		return o.Client, nil
+	}
+
+	if !o.NoAgent {
+		client, err := service.DialAgent(cmp.Or(o.AgentSocket, service.DefaultAgentSocket()))
+		if err == nil {
+			return client, nil
+		}
+	}
+
+	token := o.Token
+	if token == "" {
+		token = os.Getenv("OP_SERVICE_ACCOUNT_TOKEN")
+	}
+
+	client, err := service.NewClientFromToken(
+		ctx,
+		token,
+		service.TokenFromCommand(o.TokenCommand),
+		service.TokenFromFile(o.TokenFile),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := service.DefaultRetryPolicy()
+	if o.Retry != nil {
+		policy = *o.Retry
+	}
+
+	return service.WithLookupCache(service.WithRetry(client, policy)), nil
+}
+
+// Load reads the sections of the item. Values are typed the same way as envop export --format json,
+// so numbers, bools and JSON values are decoded and everything else is a string.
+func Load(ctx context.Context, ref Ref, opts Options) (map[string]any, error) {
+	client, err := opts.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.ReadSections(ctx, client, ref.Vault, ref.Item, ref.Sections...)
+}
+
+// LoadStrings reads the sections of the item, with the values as they would be written to an env file
+func LoadStrings(ctx context.Context, ref Ref, opts Options) (map[string]string, error) {
+	env, err := Load(ctx, ref, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.EnvironmentToStrings(env), nil
+}
+
+// Setenv reads the sections of the item into the process environment, replacing existing variables
+func Setenv(ctx context.Context, ref Ref, opts Options) error {
+	env, err := LoadStrings(ctx, ref, opts)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MustSetenv is like Setenv but panics if the secrets can't be loaded, for use at startup
+func MustSetenv(ctx context.Context, ref Ref, opts Options) {
+	if err := Setenv(ctx, ref, opts); err != nil {
+		panic(fmt.Errorf("envop: could not load %s: %w", ref, err))
+	}
+}
@@ -0,0 +1,51 @@
+package envop
+
+import (
+	"os"
+	"testing"
+
+	"github.com/yakmoose/envop/service"
+)
+
+func TestParseRef(t *testing.T) {
+	ref, err := ParseRef("op://vault/item/shared,production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ref.Vault != "vault" || ref.Item != "item" || len(ref.Sections) != 2 || ref.Sections[1] != "production" {
+		t.Errorf("Unexpected ref %v", ref)
+	}
+
+	for _, invalid := range []string{"vault/item/section", "op://vault/item", "op:///item/section"} {
+		if _, err := ParseRef(invalid); err == nil {
+			t.Errorf("Expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestSetenv(t *testing.T) {
+	ctx := t.Context()
+	client, _ := service.NewMemoryClient("vault")
+
+	vault, _ := service.FindVaultWithName(ctx, client, "vault")
+	item, _ := service.CreateItem(ctx, client, vault, "item", "shared")
+
+	shared := map[string]any{"ENVOP_TEST_A": "shared", "ENVOP_TEST_B": "shared"}
+	item, _ = service.UpdateItem(ctx, client, item, "shared", &shared)
+
+	production := map[string]any{"ENVOP_TEST_B": 2}
+	_, err := service.UpdateItem(ctx, client, item, "production", &production)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ENVOP_TEST_A", "")
+	t.Setenv("ENVOP_TEST_B", "")
+
+	MustSetenv(ctx, MustParseRef("op://vault/item/shared,production"), Options{Client: client})
+
+	if os.Getenv("ENVOP_TEST_A") != "shared" || os.Getenv("ENVOP_TEST_B") != "2" {
+		t.Errorf("Unexpected environment A=%s B=%s", os.Getenv("ENVOP_TEST_A"), os.Getenv("ENVOP_TEST_B"))
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright © 2025 John Lennard <john@yakmoo.se>
+*/
+
+package envop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/spf13/viper"
+)
+
+// ViperProvider is the name to use with viper.AddRemoteProvider
+const ViperProvider = "envop"
+
+// DefaultViperInterval is how often sections are polled when watching, unless another interval is given
+const DefaultViperInterval = 30 * time.Second
+
+// ViperReadTimeout is how long viper waits for the sections, viper has no way to cancel a read
+const ViperReadTimeout = time.Minute
+
+// viperRemoteConfig is the interface viper.RemoteConfig has to implement
+type viperRemoteConfig interface {
+	Get(rp viper.RemoteProvider) (io.Reader, error)
+	Watch(rp viper.RemoteProvider) (io.Reader, error)
+	WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool)
+}
+
+// remoteConfig reads sections for viper, handing other providers to the remote config it replaced
+type remoteConfig struct {
+	opts     Options
+	interval time.Duration
+	next     viperRemoteConfig
+
+	mu     sync.Mutex
+	client *onepassword.Client
+	last   map[string][]byte
+}
+
+// RegisterViperProvider makes envop available as a viper remote provider. The endpoint is the vault
+// and item, and the path is the section, or comma separated sections to merge:
+//
+//	envop.RegisterViperProvider(envop.Options{}, 30*time.Second)
+//	viper.AddRemoteProvider("envop", "op://vault/item", "production")
+//	viper.SetConfigType("json")
+//	err := viper.ReadRemoteConfig()
+//
+// Watching polls the item every interval, or DefaultViperInterval if it is not positive. Other providers, eg etcd from viper/remote, keep working
+// as long as their package is imported before this is called.
+func RegisterViperProvider(opts Options, interval time.Duration) {
+	if !containsProvider(viper.SupportedRemoteProviders) {
+		viper.SupportedRemoteProviders = append(viper.SupportedRemoteProviders, ViperProvider)
+	}
+
+	if interval <= 0 {
+		interval = DefaultViperInterval
+	}
+
+	next, _ := viper.RemoteConfig.(viperRemoteConfig)
+	viper.RemoteConfig = &remoteConfig{
+		opts:     opts,
+		interval: interval,
+		next:     next,
+		last:     make(map[string][]byte),
+	}
+}
+
+func containsProvider(providers []string) bool {
+	for _, provider := range providers {
+		if provider == ViperProvider {
+			return true
+		}
+	}
+	return false
+}
+
+// ref builds the reference from the provider endpoint and path
+func (r *remoteConfig) ref(rp viper.RemoteProvider) (Ref, error) {
+	return ParseRef(strings.TrimSuffix(rp.Endpoint(), "/") + "/" + strings.TrimPrefix(rp.Path(), "/"))
+}
+
+// read loads the sections as json, reusing the client between reads
+func (r *remoteConfig) read(rp viper.RemoteProvider) ([]byte, error) {
+	ref, err := r.ref(rp)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ViperReadTimeout)
+	defer cancel()
+
+	r.mu.Lock()
+	if r.client == nil {
+		r.client, err = r.opts.client(ctx)
+	}
+	opts := r.opts
+	opts.Client = r.client
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := Load(ctx, ref, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(env)
+}
+
+// changed reports whether the value differs from the last one seen for the provider, and remembers it
+func (r *remoteConfig) changed(rp viper.RemoteProvider, value []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := rp.Endpoint() + "/" + rp.Path()
+	last, seen := r.last[key]
+	r.last[key] = value
+	return seen && !bytes.Equal(last, value)
+}
+
+func (r *remoteConfig) Get(rp viper.RemoteProvider) (io.Reader, error) {
+	if rp.Provider() != ViperProvider && r.next != nil {
+		return r.next.Get(rp)
+	}
+
+	value, err := r.read(rp)
+	if err != nil {
+		return nil, err
+	}
+
+	r.changed(rp, value)
+	return bytes.NewReader(value), nil
+}
+
+// Watch blocks until the sections change, then returns them
+func (r *remoteConfig) Watch(rp viper.RemoteProvider) (io.Reader, error) {
+	if rp.Provider() != ViperProvider && r.next != nil {
+		return r.next.Watch(rp)
+	}
+
+	for {
+		value, err := r.read(rp)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.changed(rp, value) {
+			return bytes.NewReader(value), nil
+		}
+
+		time.Sleep(r.interval)
+	}
+}
+
+// WatchChannel sends the sections every time they change, until a value is sent on the quit channel
+func (r *remoteConfig) WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool) {
+	if rp.Provider() != ViperProvider && r.next != nil {
+		return r.next.WatchChannel(rp)
+	}
+
+	responses := make(chan *viper.RemoteResponse)
+	quit := make(chan bool)
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+
+			var response *viper.RemoteResponse
+			value, err := r.read(rp)
+			switch {
+			case err != nil:
+				response = &viper.RemoteResponse{Error: err}
+			case r.changed(rp, value):
+				response = &viper.RemoteResponse{Value: value}
+			default:
+				continue
+			}
+
+			// the caller may have stopped reading, so quitting has to work while sending too
+			select {
+			case responses <- response:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return responses, quit
+}
@@ -0,0 +1,115 @@
+package envop
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/yakmoose/envop/service"
+)
+
+func TestViperProvider(t *testing.T) {
+	ctx := t.Context()
+	client, _ := service.NewMemoryClient("vault")
+
+	vault, _ := service.FindVaultWithName(ctx, client, "vault")
+	item, _ := service.CreateItem(ctx, client, vault, "item", "production")
+
+	env := map[string]any{"PORT": "8080", "NAME": "api"}
+	item, err := service.UpdateItem(ctx, client, item, "production", &env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterViperProvider(Options{Client: client}, 10*time.Millisecond)
+
+	v := viper.New()
+	v.SetConfigType("json")
+	err = v.AddRemoteProvider(ViperProvider, "op://vault/item", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.ReadRemoteConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.GetInt("PORT") != 8080 || v.GetString("NAME") != "api" {
+		t.Errorf("Unexpected config PORT=%s NAME=%s", v.GetString("PORT"), v.GetString("NAME"))
+	}
+
+	changed := map[string]any{"PORT": "9090"}
+	_, err = service.UpdateItem(ctx, client, item, "production", &changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.WatchRemoteConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.GetInt("PORT") != 9090 {
+		t.Errorf("Expected the watched PORT to be 9090, got %s", v.GetString("PORT"))
+	}
+}
+
+func TestViperWatchChannelQuit(t *testing.T) {
+	ctx := t.Context()
+	client, _ := service.NewMemoryClient("vault")
+
+	vault, _ := service.FindVaultWithName(ctx, client, "vault")
+	item, _ := service.CreateItem(ctx, client, vault, "item", "production")
+
+	RegisterViperProvider(Options{Client: client}, time.Millisecond)
+
+	v := viper.New()
+	v.SetConfigType("json")
+	err := v.AddRemoteProvider(ViperProvider, "op://vault/item", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = v.ReadRemoteConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &remoteProvider{endpoint: "op://vault/item", path: "production"}
+	_, quit := viper.RemoteConfig.WatchChannel(provider)
+
+	// a change nobody reads leaves the watch sending
+	env := map[string]any{"PORT": "9090"}
+	_, err = service.UpdateItem(ctx, client, item, "production", &env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	select {
+	case quit <- true:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the watch to stop while nobody reads its responses")
+	}
+}
+
+func TestViperInterval(t *testing.T) {
+	RegisterViperProvider(Options{}, 0)
+
+	remote, ok := viper.RemoteConfig.(*remoteConfig)
+	if !ok || remote.interval != DefaultViperInterval {
+		t.Errorf("Expected an interval of 0 to use the default, got %v", viper.RemoteConfig)
+	}
+}
+
+// remoteProvider is what viper hands to the remote config
+type remoteProvider struct {
+	endpoint string
+	path     string
+}
+
+func (p *remoteProvider) Provider() string      { return ViperProvider }
+func (p *remoteProvider) Endpoint() string      { return p.endpoint }
+func (p *remoteProvider) Path() string          { return p.path }
+func (p *remoteProvider) SecretKeyring() string { return "" }
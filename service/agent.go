@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// DefaultAgentCallTimeout is how long the agent spends on a single call before giving up
+const DefaultAgentCallTimeout = 2 * time.Minute
+
+// DefaultAgentSocket is where the agent listens unless agent_socket is configured. Without
+// XDG_RUNTIME_DIR there is no private directory to put it in, so it is empty and the agent is not used.
+func DefaultAgentSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "envop", "agent.sock")
+	}
+	return ""
+}
+
+// ErrNoAgentSocket is returned when there is no socket to serve or dial the agent on
+var ErrNoAgentSocket = errors.New("no agent socket, set XDG_RUNTIME_DIR or agent_socket")
+
+// checkPrivate makes sure the path belongs to the current user, and for a directory that nobody else can use it
+func checkPrivate(path string, dir bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	if dir && !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	if !dir && info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%s is not a socket", path)
+	}
+
+	return checkAccess(path, info)
+}
+
+// AgentArgs are the arguments of every agent call, each call uses the fields it needs
+type AgentArgs struct {
+	VaultID string
+	ItemID  string
+	Item    onepassword.Item
+	Params  onepassword.ItemCreateParams
+	Filters []onepassword.ItemListFilter
+}
+
+type cached[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// Agent serves a 1password client over rpc, caching the vault and item lists used to look items up by
+// name for the ttl. Items are always fetched fresh, so polling readers see rotated secrets straight away.
+// Changes made through the agent drop the cached lists they affect.
+type Agent struct {
+	// CallTimeout is how long each call may take, the caller may have given up by then
+	CallTimeout time.Duration
+
+	client *onepassword.Client
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	vaults *cached[[]onepassword.VaultOverview]
+	lists  map[string]cached[[]onepassword.ItemOverview]
+}
+
+// NewAgent creates an agent for the client
+func NewAgent(client *onepassword.Client, ttl time.Duration) *Agent {
+	return &Agent{
+		CallTimeout: DefaultAgentCallTimeout,
+		client:      client,
+		ttl:         ttl,
+		lists:       make(map[string]cached[[]onepassword.ItemOverview]),
+	}
+}
+
+// context bounds a call, the rpc package does not pass on the cancellation of the caller
+func (a *Agent) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), a.CallTimeout)
+}
+
+func (a *Agent) ListVaults(_ AgentArgs, reply *[]onepassword.VaultOverview) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	a.mu.Lock()
+	entry := a.vaults
+	a.mu.Unlock()
+
+	if entry != nil && time.Now().Before(entry.expires) {
+		*reply = entry.value
+		return nil
+	}
+
+	vaults, err := a.client.Vaults().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.vaults = &cached[[]onepassword.VaultOverview]{value: vaults, expires: time.Now().Add(a.ttl)}
+	a.mu.Unlock()
+
+	*reply = vaults
+	return nil
+}
+
+func (a *Agent) ListItems(args AgentArgs, reply *[]onepassword.ItemOverview) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	// filtered lists are rare, and not worth caching
+	if len(args.Filters) > 0 {
+		items, err := a.client.Items().List(ctx, args.VaultID, args.Filters...)
+		*reply = items
+		return err
+	}
+
+	a.mu.Lock()
+	entry, ok := a.lists[args.VaultID]
+	a.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		*reply = entry.value
+		return nil
+	}
+
+	items, err := a.client.Items().List(ctx, args.VaultID)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.lists[args.VaultID] = cached[[]onepassword.ItemOverview]{value: items, expires: time.Now().Add(a.ttl)}
+	a.mu.Unlock()
+
+	*reply = items
+	return nil
+}
+
+func (a *Agent) GetItem(args AgentArgs, reply *onepassword.Item) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	item, err := a.client.Items().Get(ctx, args.VaultID, args.ItemID)
+	*reply = item
+	return err
+}
+
+func (a *Agent) CreateItem(args AgentArgs, reply *onepassword.Item) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	item, err := a.client.Items().Create(ctx, args.Params)
+	a.forget(args.Params.VaultID)
+
+	*reply = item
+	return err
+}
+
+// AgentPutReply carries conflicts back as a value, since rpc errors are only strings
+type AgentPutReply struct {
+	Item     onepassword.Item
+	Conflict *ConflictError
+}
+
+// PutItem checks the version against the stored item, so changes made elsewhere are still detected
+func (a *Agent) PutItem(args AgentArgs, reply *AgentPutReply) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	item, err := putItem(ctx, a.client, args.Item)
+	a.forget(args.Item.VaultID)
+
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		reply.Conflict = conflict
+		return nil
+	}
+
+	reply.Item = item
+	return err
+}
+
+func (a *Agent) DeleteItem(args AgentArgs, _ *struct{}) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	err := a.client.Items().Delete(ctx, args.VaultID, args.ItemID)
+	a.forget(args.VaultID)
+	return err
+}
+
+func (a *Agent) ArchiveItem(args AgentArgs, _ *struct{}) error {
+	ctx, cancel := a.context()
+	defer cancel()
+
+	err := a.client.Items().Archive(ctx, args.VaultID, args.ItemID)
+	a.forget(args.VaultID)
+	return err
+}
+
+// forget drops the item list of the vault
+func (a *Agent) forget(vaultID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.lists, vaultID)
+}
+
+// Serve listens on the socket until the context is done. The socket is only accessible to the current user.
+func (a *Agent) Serve(ctx context.Context, socketPath string) error {
+	if socketPath == "" {
+		return ErrNoAgentSocket
+	}
+
+	err := os.MkdirAll(filepath.Dir(socketPath), 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = checkPrivate(filepath.Dir(socketPath), true)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return fmt.Errorf("an agent is already listening on %s", socketPath)
+		}
+		os.Remove(socketPath)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	err = os.Chmod(socketPath, 0o600)
+	if err != nil {
+		return err
+	}
+
+	server := rpc.NewServer()
+	err = server.RegisterName("Agent", a)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// DialAgent connects to the agent on the socket, and returns a client that makes its calls through it.
+// The socket and its directory must belong to the current user, so calls never go to someone else's agent.
+func DialAgent(socketPath string) (*onepassword.Client, error) {
+	if socketPath == "" {
+		return nil, ErrNoAgentSocket
+	}
+
+	err := checkPrivate(filepath.Dir(socketPath), true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPrivate(socketPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcClient := jsonrpc.NewClient(conn)
+	return &onepassword.Client{
+		ItemsAPI:  &agentItems{client: rpcClient},
+		VaultsAPI: &agentVaults{client: rpcClient},
+	}, nil
+}
+
+// callAgent makes the call, giving up when the context is done
+func callAgent(ctx context.Context, client *rpc.Client, method string, args AgentArgs, reply any) error {
+	call := client.Go("Agent."+method, args, reply, make(chan *rpc.Call, 1))
+
+	select {
+	case <-call.Done:
+		if errors.Is(call.Error, rpc.ErrShutdown) {
+			return fmt.Errorf("the envop agent went away: %w", call.Error)
+		}
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type agentVaults struct {
+	client *rpc.Client
+}
+
+func (v *agentVaults) List(ctx context.Context) ([]onepassword.VaultOverview, error) {
+	var vaults []onepassword.VaultOverview
+	err := callAgent(ctx, v.client, "ListVaults", AgentArgs{}, &vaults)
+	return vaults, err
+}
+
+type agentItems struct {
+	client *rpc.Client
+}
+
+func (i *agentItems) Create(ctx context.Context, params onepassword.ItemCreateParams) (onepassword.Item, error) {
+	var item onepassword.Item
+	err := callAgent(ctx, i.client, "CreateItem", AgentArgs{Params: params}, &item)
+	return item, err
+}
+
+func (i *agentItems) Get(ctx context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	var item onepassword.Item
+	err := callAgent(ctx, i.client, "GetItem", AgentArgs{VaultID: vaultID, ItemID: itemID}, &item)
+	return item, err
+}
+
+func (i *agentItems) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	var reply AgentPutReply
+	err := callAgent(ctx, i.client, "PutItem", AgentArgs{Item: item}, &reply)
+	if err == nil && reply.Conflict != nil {
+		return onepassword.Item{}, reply.Conflict
+	}
+	return reply.Item, err
+}
+
+func (i *agentItems) Delete(ctx context.Context, vaultID string, itemID string) error {
+	return callAgent(ctx, i.client, "DeleteItem", AgentArgs{VaultID: vaultID, ItemID: itemID}, &struct{}{})
+}
+
+func (i *agentItems) Archive(ctx context.Context, vaultID string, itemID string) error {
+	return callAgent(ctx, i.client, "ArchiveItem", AgentArgs{VaultID: vaultID, ItemID: itemID}, &struct{}{})
+}
+
+func (i *agentItems) List(ctx context.Context, vaultID string, filters ...onepassword.ItemListFilter) ([]onepassword.ItemOverview, error) {
+	var items []onepassword.ItemOverview
+	err := callAgent(ctx, i.client, "ListItems", AgentArgs{VaultID: vaultID, Filters: filters}, &items)
+	return items, err
+}
+
+func (i *agentItems) Shares() onepassword.ItemsSharesAPI {
+	return nil
+}
+
+func (i *agentItems) Files() onepassword.ItemsFilesAPI {
+	return nil
+}
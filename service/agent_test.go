@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// countingVaults counts the calls to List
+type countingVaults struct {
+	onepassword.VaultsAPI
+	lists int
+}
+
+func (c *countingVaults) List(ctx context.Context) ([]onepassword.VaultOverview, error) {
+	c.lists++
+	return c.VaultsAPI.List(ctx)
+}
+
+func startAgent(t *testing.T, client *onepassword.Client) *onepassword.Client {
+	ctx, cancel := context.WithCancel(t.Context())
+	socketPath := filepath.Join(t.TempDir(), "envop", "agent.sock")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewAgent(client, time.Minute).Serve(ctx, socketPath)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	for range 100 {
+		agentClient, err := DialAgent(socketPath)
+		if err == nil {
+			return agentClient
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Expected the agent to start")
+	return nil
+}
+
+func TestAgent(t *testing.T) {
+	ctx := t.Context()
+	client, store := NewMemoryClient("vault")
+	vaults := &countingVaults{VaultsAPI: client.VaultsAPI}
+	client.VaultsAPI = vaults
+
+	item := newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+	agentClient := startAgent(t, client)
+
+	for range 3 {
+		env, err := ReadOnePassword(ctx, agentClient, "vault", "item", "production")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if env["A"] != int64(1) {
+			t.Errorf("Expected A to be 1, got %v", env["A"])
+		}
+	}
+
+	if vaults.lists != 2 {
+		t.Errorf("Expected the vaults to be listed once by the agent, got %d lists", vaults.lists)
+	}
+
+	store.Touch(item.ID)
+
+	environment := map[string]any{"A": "2"}
+	_, err := UpdateItem(ctx, agentClient, item, "production", &environment)
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected a conflict through the agent, got %v", err)
+	}
+}
+
+// blockingItems never answers a get, until the context is done
+type blockingItems struct {
+	onepassword.ItemsAPI
+}
+
+func (b *blockingItems) Get(ctx context.Context, _ string, _ string) (onepassword.Item, error) {
+	<-ctx.Done()
+	return onepassword.Item{}, ctx.Err()
+}
+
+func TestAgentCallTimeout(t *testing.T) {
+	client, _ := NewMemoryClient("vault")
+	client.ItemsAPI = &blockingItems{ItemsAPI: client.ItemsAPI}
+
+	agent := NewAgent(client, time.Minute)
+	agent.CallTimeout = 10 * time.Millisecond
+
+	var item onepassword.Item
+	err := agent.GetItem(AgentArgs{VaultID: "vault", ItemID: "item"}, &item)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the call to time out, got %v", err)
+	}
+}
+
+func TestAgentSocketOwnership(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there are no unix permissions on windows")
+	}
+
+	t.Run("no socket", func(t *testing.T) {
+		if _, err := DialAgent(""); !errors.Is(err, ErrNoAgentSocket) {
+			t.Errorf("Expected ErrNoAgentSocket, got %v", err)
+		}
+		if err := NewAgent(nil, time.Minute).Serve(t.Context(), ""); !errors.Is(err, ErrNoAgentSocket) {
+			t.Errorf("Expected ErrNoAgentSocket, got %v", err)
+		}
+	})
+
+	t.Run("shared directory", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.Chmod(dir, 0o777)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		socketPath := filepath.Join(dir, "agent.sock")
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		if _, err := DialAgent(socketPath); err == nil {
+			t.Error("Expected a socket in a shared directory to be refused")
+		}
+		if err := NewAgent(nil, time.Minute).Serve(t.Context(), filepath.Join(dir, "other.sock")); err == nil {
+			t.Error("Expected the agent to refuse to listen in a shared directory")
+		}
+	})
+
+	t.Run("not a socket", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.Chmod(dir, 0o700)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		socketPath := filepath.Join(dir, "agent.sock")
+		err = os.WriteFile(socketPath, nil, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := DialAgent(socketPath); err == nil {
+			t.Error("Expected a file that is not a socket to be refused")
+		}
+	})
+}
+
+func TestAgentFreshItems(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	item := newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+	agentClient := startAgent(t, client)
+
+	env, err := ReadOnePassword(ctx, agentClient, "vault", "item", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["A"] != int64(1) {
+		t.Fatalf("Expected A to be 1, got %v", env["A"])
+	}
+
+	// rotated by someone else, not through the agent
+	environment := map[string]any{"A": "2"}
+	_, err = UpdateItem(ctx, client, item, "production", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err = ReadOnePassword(ctx, agentClient, "vault", "item", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["A"] != int64(2) {
+		t.Errorf("Expected the rotated value 2 straight away, got %v", env["A"])
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkAccess fails unless the file belongs to the current user, and a directory is not open to anyone else
+func checkAccess(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("could not find the owner of %s", path)
+	}
+
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s belongs to uid %d, not the current user", path, stat.Uid)
+	}
+
+	if info.IsDir() && info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is accessible to other users, its mode is %04o and should be 0700", path, info.Mode().Perm())
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"filippo.io/age"
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// Backup is a full copy of the items in a vault, including their sections, field types and categories
+type Backup struct {
+	Version   int                `json:"version"`
+	Vault     string             `json:"vault"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Items     []onepassword.Item `json:"items"`
+}
+
+// RestoreReport lists what happened to each item in a backup during a restore
+type RestoreReport struct {
+	Created []string
+	Skipped []string
+}
+
+// BackupVault reads every item in the vault
+func BackupVault(ctx context.Context, client *onepassword.Client, vault *onepassword.VaultOverview) (*Backup, error) {
+	overviews, err := client.Items().List(ctx, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &Backup{
+		Version:   1,
+		Vault:     vault.Title,
+		CreatedAt: time.Now().UTC(),
+		Items:     make([]onepassword.Item, 0, len(overviews)),
+	}
+
+	for _, overview := range overviews {
+		item, err := client.Items().Get(ctx, vault.ID, overview.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not read item %s: %w", overview.Title, err)
+		}
+		backup.Items = append(backup.Items, item)
+	}
+
+	return backup, nil
+}
+
+// WriteBackup encrypts the backup to the age recipients, which can include an scrypt passphrase recipient
+func WriteBackup(w io.Writer, backup *Backup, recipients ...age.Recipient) error {
+	encrypted, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(encrypted).Encode(backup)
+	if err != nil {
+		return err
+	}
+
+	return encrypted.Close()
+}
+
+// WriteBackupFile writes the encrypted backup to a temporary file next to the archive and renames it into
+// place once it is synced, so a failure never replaces an earlier backup with a partial one
+func WriteBackupFile(fileName string, backup *Backup, recipients ...age.Recipient) error {
+	fh, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	tmpName := fh.Name()
+	defer os.Remove(tmpName)
+	defer fh.Close()
+
+	err = WriteBackup(fh, backup, recipients...)
+	if err != nil {
+		return err
+	}
+
+	err = fh.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = fh.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, fileName)
+}
+
+// ReadBackup decrypts a backup written by WriteBackup
+func ReadBackup(r io.Reader, identities ...age.Identity) (*Backup, error) {
+	decrypted, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	var backup Backup
+	err = json.NewDecoder(decrypted).Decode(&backup)
+	if err != nil {
+		return nil, err
+	}
+
+	if backup.Version != 1 {
+		return nil, fmt.Errorf("unsupported backup version %d", backup.Version)
+	}
+
+	return &backup, nil
+}
+
+// RestoreBackup recreates the items from the backup in the vault, optionally skipping items whose title already exists
+func RestoreBackup(
+	ctx context.Context,
+	client *onepassword.Client,
+	backup *Backup,
+	vault *onepassword.VaultOverview,
+	skipExisting bool,
+) (*RestoreReport, error) {
+	existing := make(map[string]bool)
+	if skipExisting {
+		overviews, err := client.Items().List(ctx, vault.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, overview := range overviews {
+			existing[overview.Title] = true
+		}
+	}
+
+	report := &RestoreReport{}
+	for _, item := range backup.Items {
+		if existing[item.Title] {
+			report.Skipped = append(report.Skipped, item.Title)
+			continue
+		}
+
+		itemParams := onepassword.ItemCreateParams{
+			Title:    item.Title,
+			Category: item.Category,
+			VaultID:  vault.ID,
+			Sections: item.Sections,
+			Fields:   item.Fields,
+			Notes:    &item.Notes,
+			Tags:     item.Tags,
+			Websites: item.Websites,
+		}
+
+		_, err := client.Items().Create(ctx, itemParams)
+		if err != nil {
+			return report, fmt.Errorf("could not restore item %s: %w", item.Title, err)
+		}
+		report.Created = append(report.Created, item.Title)
+	}
+
+	return report, nil
+}
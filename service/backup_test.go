@@ -0,0 +1,145 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := t.Context()
+	client, store := NewMemoryClient("vault")
+	restored := store.AddVault("restored")
+
+	newItemWithSection(t, ctx, client, "first", "production", map[string]any{"A": "1"})
+	newItemWithSection(t, ctx, client, "second", "staging", map[string]any{"B": "2"})
+
+	vault, err := FindVaultWithName(ctx, client, "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := BackupVault(ctx, client, vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup.age")
+	err = WriteBackupFile(archive, backup, identity.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		fh, err := os.Open(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+
+		read, err := ReadBackup(fh, identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := RestoreBackup(ctx, client, read, &restored, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Created) != 2 {
+			t.Errorf("Expected 2 items to be restored, got %v", report.Created)
+		}
+
+		item, err := FindItemWithName(ctx, client, &restored, "first")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values := sectionValues(item, "production"); values["A"] != "1" {
+			t.Errorf("Expected A=1 in the restored item, got %v", values)
+		}
+
+		report, err = RestoreBackup(ctx, client, read, &restored, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Created) != 0 || len(report.Skipped) != 2 {
+			t.Errorf("Expected existing items to be skipped, got %+v", report)
+		}
+	})
+
+	t.Run("wrong identity", func(t *testing.T) {
+		other, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fh, err := os.Open(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+
+		if _, err := ReadBackup(fh, other); err == nil {
+			t.Errorf("Expected the backup to be unreadable with another identity")
+		}
+	})
+
+	t.Run("corrupt archive", func(t *testing.T) {
+		raw, err := os.ReadFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		corrupt := filepath.Join(t.TempDir(), "corrupt.age")
+		raw[len(raw)-1] ^= 0xff
+		err = os.WriteFile(corrupt, raw, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fh, err := os.Open(corrupt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+
+		if _, err := ReadBackup(fh, identity); err == nil {
+			t.Errorf("Expected a corrupt backup to be refused")
+		}
+	})
+
+	t.Run("failed write keeps the previous archive", func(t *testing.T) {
+		before, err := os.ReadFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// age refuses to encrypt without recipients
+		if err := WriteBackupFile(archive, backup); err == nil {
+			t.Fatal("Expected the backup to fail without recipients")
+		}
+
+		after, err := os.ReadFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(after) != string(before) {
+			t.Errorf("Expected the previous archive to be left alone")
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(archive))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("Expected the temporary file to be removed, found %d files", len(entries))
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/1password/onepassword-sdk-go"
+	"golang.org/x/sync/errgroup"
+)
+
+// formatExtensions are the file extensions used for each export format
+var formatExtensions = map[string]string{
+	"env":    ".env",
+	"json":   ".json",
+	"tfvars": ".tfvars",
+	"tfvar":  ".tfvars",
+	"hcl":    ".hcl",
+}
+
+// BulkExport exports the sections of every item matching a glob into a directory per item
+type BulkExport struct {
+	// Glob matches item titles, see path.Match
+	Glob string
+	// Section only exports this section of each item, the default is every section
+	Section string
+	OutDir  string
+	Format  string
+	// Validate is called with each section before it is written, if set
+	Validate func(sectionName string, env map[string]any) error
+}
+
+// ExportResult is what happened to one section of a bulk export
+type ExportResult struct {
+	Item    string
+	Section string
+	Path    string
+	Keys    int
+	Err     error
+}
+
+// exportFileName makes an item or section title safe to use as a file name
+func exportFileName(title string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(title))
+	if name == "" || strings.HasPrefix(name, ".") {
+		name = "_" + name
+	}
+	return name
+}
+
+// Run exports the matching items, at most Workers at a time. Failures are recorded in the results
+// rather than stopping the other exports, the results are sorted by item and section.
+func (b BulkExport) Run(ctx context.Context, client *onepassword.Client, vault *onepassword.VaultOverview) ([]ExportResult, error) {
+	extension, ok := formatExtensions[b.Format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %s, expected env, json or tfvars", b.Format)
+	}
+
+	if _, err := path.Match(b.Glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid item glob %s: %w", b.Glob, err)
+	}
+
+	overviews, err := client.Items().List(ctx, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// items are exported concurrently, so two that would be written to the same directory must be caught first
+	var matching []onepassword.ItemOverview
+	directories := make(map[string]string)
+	for _, overview := range overviews {
+		if matched, _ := path.Match(b.Glob, overview.Title); !matched {
+			continue
+		}
+
+		// some file systems ignore case
+		directory := strings.ToLower(exportFileName(overview.Title))
+		if other, ok := directories[directory]; ok {
+			return nil, fmt.Errorf("items %s and %s would both be exported to %s", other, overview.Title, exportFileName(overview.Title))
+		}
+		directories[directory] = overview.Title
+		matching = append(matching, overview)
+	}
+
+	var mu sync.Mutex
+	var results []ExportResult
+	report := func(result ExportResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, result)
+	}
+
+	var group errgroup.Group
+	group.SetLimit(Workers)
+
+	for _, overview := range matching {
+		group.Go(func() error {
+			item, err := client.Items().Get(ctx, vault.ID, overview.ID)
+			if err != nil {
+				report(ExportResult{Item: overview.Title, Section: b.Section, Err: err})
+				return nil
+			}
+
+			for _, result := range b.exportItem(&item, extension) {
+				report(result)
+			}
+			return nil
+		})
+	}
+	group.Wait()
+
+	slices.SortFunc(results, func(a ExportResult, b ExportResult) int {
+		return cmp.Or(strings.Compare(a.Item, b.Item), strings.Compare(a.Section, b.Section))
+	})
+	return results, ctx.Err()
+}
+
+// exportItem writes each section of the item to <out dir>/<item>/<section><extension>
+func (b BulkExport) exportItem(item *onepassword.Item, extension string) []ExportResult {
+	var sectionNames []string
+	if b.Section != "" {
+		sectionNames = []string{b.Section}
+	} else {
+		for _, section := range item.Sections {
+			sectionNames = append(sectionNames, section.Title)
+		}
+	}
+
+	results := make([]ExportResult, 0, len(sectionNames))
+	files := make(map[string]string, len(sectionNames))
+	for _, sectionName := range sectionNames {
+		result := ExportResult{
+			Item:    item.Title,
+			Section: sectionName,
+			Path:    filepath.Join(b.OutDir, exportFileName(item.Title), exportFileName(sectionName)+extension),
+		}
+
+		// don't overwrite the file of a section whose title maps to the same name
+		file := strings.ToLower(exportFileName(sectionName))
+		if other, ok := files[file]; ok {
+			result.Err = fmt.Errorf("sections %s and %s would both be exported to %s", other, sectionName, result.Path)
+			results = append(results, result)
+			continue
+		}
+		files[file] = sectionName
+
+		env, err := ItemEnvironment(item, sectionName)
+		if err == nil && b.Validate != nil {
+			err = b.Validate(sectionName, env)
+		}
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(result.Path), 0o700)
+		}
+		if err == nil {
+			err = WriteAtomic(result.Path, b.Format, env)
+		}
+
+		result.Keys = len(env)
+		result.Err = err
+		results = append(results, result)
+	}
+
+	return results
+}
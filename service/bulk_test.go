@@ -0,0 +1,94 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBulkExport(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	outDir := t.TempDir()
+
+	api := newItemWithSection(t, ctx, client, "svc-api", "production", map[string]any{"A": "1", "B": "2"})
+	staging := map[string]any{"A": "0"}
+	_, err := UpdateItem(ctx, client, api, "staging", &staging)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newItemWithSection(t, ctx, client, "svc-web", "production", map[string]any{"C": "3"})
+	newItemWithSection(t, ctx, client, "other", "production", map[string]any{"D": "4"})
+
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+	export := BulkExport{Glob: "svc-*", OutDir: outDir, Format: "env"}
+
+	results, err := export.Run(ctx, client, vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 sections to be exported, got %v", results)
+	}
+
+	expected := []struct{ item, section string }{{"svc-api", "production"}, {"svc-api", "staging"}, {"svc-web", "production"}}
+	for i, result := range results {
+		if result.Err != nil || result.Item != expected[i].item || result.Section != expected[i].section {
+			t.Errorf("Unexpected result %v", result)
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join(outDir, "svc-web", "production.env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "C=\"3\"\n" {
+		t.Errorf("Unexpected file content %q", raw)
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "other")); err == nil {
+		t.Errorf("Expected items that don't match the glob to be skipped")
+	}
+}
+
+func TestBulkExportClashes(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+
+	t.Run("items", func(t *testing.T) {
+		outDir := t.TempDir()
+		newItemWithSection(t, ctx, client, "svc-api", "production", map[string]any{"A": "1"})
+		newItemWithSection(t, ctx, client, "SVC-API", "production", map[string]any{"A": "2"})
+
+		export := BulkExport{Glob: "[sS][vV][cC]-*", OutDir: outDir, Format: "env"}
+		if _, err := export.Run(ctx, client, vault); err == nil {
+			t.Errorf("Expected items exported to the same directory to fail")
+		}
+
+		if entries, _ := os.ReadDir(outDir); len(entries) != 0 {
+			t.Errorf("Expected nothing to be exported, found %v", entries)
+		}
+	})
+
+	t.Run("sections", func(t *testing.T) {
+		outDir := t.TempDir()
+		item := newItemWithSection(t, ctx, client, "web", "prod/eu", map[string]any{"A": "1"})
+		other := map[string]any{"A": "2"}
+		if _, err := UpdateItem(ctx, client, item, "prod_eu", &other); err != nil {
+			t.Fatal(err)
+		}
+
+		export := BulkExport{Glob: "web", OutDir: outDir, Format: "env"}
+		results, err := export.Run(ctx, client, vault)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results) != 2 || (results[0].Err == nil) == (results[1].Err == nil) {
+			t.Errorf("Expected one of the clashing sections to fail, got %v", results)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CascadeFile is one file of a cascade, keys in later files replace the same keys in earlier ones
+type CascadeFile struct {
+	// Pattern is the file to read, {path} is replaced by the env file and {env} by the environment.
+	// Patterns with {env} are skipped when there is no environment.
+	Pattern string
+	// Fallback is read instead when the file doesn't exist, eg .env.dist for .env in symfony
+	Fallback string
+	// SkipIn lists the environments the file is not read in, eg .env.local in test
+	SkipIn []string
+}
+
+// Cascade is the order the files of an environment are read in
+type Cascade struct {
+	Name  string
+	Files []CascadeFile
+}
+
+// Cascades are the presets for --cascade
+var Cascades = map[string]Cascade{
+	// default reads .env, .env.local, .env.<env> and .env.<env>.local
+	"default": {Name: "default", Files: []CascadeFile{
+		{Pattern: "{path}"},
+		{Pattern: "{path}.local"},
+		{Pattern: "{path}.{env}"},
+		{Pattern: "{path}.{env}.local"},
+	}},
+	// symfony reads .env, or .env.dist when there is no .env, then .env.local except in test, .env.<env> and .env.<env>.local
+	"symfony": {Name: "symfony", Files: []CascadeFile{
+		{Pattern: "{path}", Fallback: "{path}.dist"},
+		{Pattern: "{path}.local", SkipIn: []string{"test"}},
+		{Pattern: "{path}.{env}"},
+		{Pattern: "{path}.{env}.local"},
+	}},
+	// dotenv-flow reads .env, .env.local except in test, .env.<env> and .env.<env>.local
+	"dotenv-flow": {Name: "dotenv-flow", Files: []CascadeFile{
+		{Pattern: "{path}"},
+		{Pattern: "{path}.local", SkipIn: []string{"test"}},
+		{Pattern: "{path}.{env}"},
+		{Pattern: "{path}.{env}.local"},
+	}},
+	// rails reads .env, .env.<env>, .env.local except in test, and .env.<env>.local, as dotenv-rails does
+	"rails": {Name: "rails", Files: []CascadeFile{
+		{Pattern: "{path}"},
+		{Pattern: "{path}.{env}"},
+		{Pattern: "{path}.local", SkipIn: []string{"test"}},
+		{Pattern: "{path}.{env}.local"},
+	}},
+}
+
+// DefaultCascade is the cascade used when none is given
+var DefaultCascade = Cascades["default"]
+
+// ParseCascade parses a preset name, or custom: followed by a comma separated list of patterns,
+// eg custom:{path},{path}.{env}
+func ParseCascade(spec string) (Cascade, error) {
+	if spec == "" {
+		return DefaultCascade, nil
+	}
+
+	if patterns, ok := strings.CutPrefix(spec, "custom:"); ok {
+		cascade := Cascade{Name: spec}
+		for _, pattern := range strings.Split(patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				return Cascade{}, fmt.Errorf("cascade %s has an empty pattern", spec)
+			}
+			cascade.Files = append(cascade.Files, CascadeFile{Pattern: pattern})
+		}
+		return cascade, nil
+	}
+
+	cascade, ok := Cascades[spec]
+	if !ok {
+		return Cascade{}, fmt.Errorf("unknown cascade %s, expected default, symfony, dotenv-flow, rails or custom:<patterns>", spec)
+	}
+	return cascade, nil
+}
+
+// expand replaces {path} and {env} in the pattern, it returns false when the pattern needs an environment and there is none
+func expand(pattern string, envName string, path string) (string, bool) {
+	if envName == "" && strings.Contains(pattern, "{env}") {
+		return "", false
+	}
+	return strings.NewReplacer("{path}", path, "{env}", envName).Replace(pattern), true
+}
+
+// Paths returns the files to read for the environment, in order. Files that don't exist are left to the reader
+// to skip, apart from a fallback, which is used when its file doesn't exist.
+func (c Cascade) Paths(envName string, path string) []string {
+	return c.paths(envName, path, "")
+}
+
+// StructuredPaths is Paths for files where the extension matters, like config.json. The patterns are applied to
+// the name without the extension, so config.json is followed by config.<env>.json rather than config.json.<env>.
+func (c Cascade) StructuredPaths(envName string, path string) []string {
+	extension := filepath.Ext(path)
+	return c.paths(envName, strings.TrimSuffix(path, extension), extension)
+}
+
+func (c Cascade) paths(envName string, path string, extension string) []string {
+	var paths []string
+	for _, file := range c.Files {
+		if slices.Contains(file.SkipIn, envName) {
+			continue
+		}
+
+		expanded, ok := expand(file.Pattern, envName, path)
+		if !ok {
+			continue
+		}
+		expanded += extension
+
+		if file.Fallback != "" {
+			if _, err := os.Stat(expanded); os.IsNotExist(err) {
+				if fallback, ok := expand(file.Fallback, envName, path); ok {
+					expanded = fallback + extension
+				}
+			}
+		}
+
+		paths = append(paths, expanded)
+	}
+	return paths
+}
+
+// ReadStructuredCascade reads the JSON or tfvars files of the cascade with readFile, skipping the files that don't
+// exist, and deep merges them so nested objects keep the keys later files don't set. The sources are the
+// file each key came from, with nested keys joined by dots.
+func ReadStructuredCascade(
+	cascade Cascade,
+	envName string,
+	path string,
+	readFile func(path string) (map[string]any, error),
+) (map[string]any, map[string]string, error) {
+	env := make(map[string]any)
+	sources := make(map[string]string)
+
+	found := false
+	for _, fileName := range cascade.StructuredPaths(envName, path) {
+		fileEnv, err := readFile(fileName)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not read %s: %w", fileName, err)
+		}
+
+		found = true
+		deepMerge(env, fileEnv, "", fileName, sources)
+	}
+
+	if !found {
+		return nil, nil, fmt.Errorf("none of the files for %s exist", path)
+	}
+
+	return env, sources, nil
+}
+
+// deepMerge merges src into dst, objects are merged key by key and any other value replaces the one in dst
+func deepMerge(dst map[string]any, src map[string]any, prefix string, fileName string, sources map[string]string) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+
+		if srcIsMap != dstIsMap {
+			// the value changes shape, so forget where the old one came from
+			delete(sources, prefix+key)
+			for source := range sources {
+				if strings.HasPrefix(source, prefix+key+".") {
+					delete(sources, source)
+				}
+			}
+		}
+
+		if srcIsMap {
+			if !dstIsMap {
+				dstMap = make(map[string]any)
+				dst[key] = dstMap
+			}
+			deepMerge(dstMap, srcMap, prefix+key+".", fileName, sources)
+			continue
+		}
+
+		dst[key] = value
+		sources[prefix+key] = fileName
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadEnvCascade(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".env.dist":       "A=dist\nB=dist\n",
+		".env.local":      "B=local\nC=local\n",
+		".env.production": "C=production\n",
+		".env.test":       "C=test\nD=test\n",
+		".env.test.local": "D=test-local\n",
+	})
+	path := filepath.Join(dir, ".env")
+
+	cases := []struct {
+		cascade  string
+		envName  string
+		expected map[string]string
+	}{
+		// .env doesn't exist, so symfony falls back to .env.dist, and skips .env.local in test
+		{"symfony", "test", map[string]string{"A": ".env.dist", "B": ".env.dist", "C": ".env.test", "D": ".env.test.local"}},
+		{"dotenv-flow", "production", map[string]string{"A": "", "B": ".env.local", "C": ".env.production"}},
+		// rails reads .env.local after .env.<env>, except in test
+		{"rails", "production", map[string]string{"B": ".env.local", "C": ".env.local"}},
+		{"rails", "test", map[string]string{"B": "", "C": ".env.test", "D": ".env.test.local"}},
+		{"default", "production", map[string]string{"B": ".env.local", "C": ".env.production", "D": ""}},
+		{"custom:{path}.dist,{path}.{env}", "test", map[string]string{"A": ".env.dist", "C": ".env.test", "D": ".env.test"}},
+	}
+
+	for _, c := range cases {
+		cascade, err := ParseCascade(c.cascade)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, sources, err := ReadEnvCascade(cascade, c.envName, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for key, file := range c.expected {
+			if file != "" {
+				file = filepath.Join(dir, file)
+			}
+			if sources[key] != file {
+				t.Errorf("Expected %s to come from %q with %s in %s, got %q", key, file, c.cascade, c.envName, sources[key])
+			}
+		}
+	}
+}
+
+func TestReadEnvErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+
+	env, err := ReadEnv("production", path)
+	if err != nil || len(env) != 0 {
+		t.Errorf("Expected missing files to be skipped, got %v %v", env, err)
+	}
+
+	// a directory can be opened but not read
+	if err := os.Mkdir(path+".local", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadEnv("production", path); err == nil {
+		t.Errorf("Expected a file that can't be read to fail")
+	}
+
+	if _, err := ParseCascade("laravel"); err == nil {
+		t.Errorf("Expected an unknown cascade to fail")
+	}
+}
+
+func TestReadStructuredCascade(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.json":                 `{"name": "api", "db": {"host": "localhost", "port": 5432}, "tags": ["a"]}`,
+		"config.production.json":      `{"db": {"host": "db.internal"}, "tags": ["b"]}`,
+		"terraform.tfvars":            "region = \"eu-west-1\"\ncount = 1\n",
+		"terraform.production.tfvars": "count = 3\n",
+	})
+
+	env, sources, err := ReadCascade("json", DefaultCascade, "production", filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := env["db"].(map[string]any)
+	if db["host"] != "db.internal" || db["port"] != float64(5432) || env["name"] != "api" {
+		t.Errorf("Expected the nested objects to be merged, got %v", env)
+	}
+
+	if tags := env["tags"].([]any); len(tags) != 1 || tags[0] != "b" {
+		t.Errorf("Expected arrays to be replaced, got %v", env["tags"])
+	}
+
+	if sources["db.host"] != filepath.Join(dir, "config.production.json") || sources["db.port"] != filepath.Join(dir, "config.json") {
+		t.Errorf("Unexpected sources %v", sources)
+	}
+
+	env, err = ReadHcl("production", filepath.Join(dir, "terraform.tfvars"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env["region"] != "eu-west-1" || anyToStringish(env["count"]) != "3" {
+		t.Errorf("Expected the tfvars to cascade, got %v", env)
+	}
+
+	if _, err := ReadJson("production", filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("Expected an error when none of the files exist")
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/google/uuid"
+)
+
+// CloneParams builds the parameters to create a copy of the item, with the same category, notes and tags,
+// every section and every field, including fields that are not in a section. Sections and fields get new ids.
+func CloneParams(
+	sourceItem *onepassword.Item,
+	vault *onepassword.VaultOverview,
+	itemName string,
+) onepassword.ItemCreateParams {
+	sectionIDs := make(map[string]string, len(sourceItem.Sections))
+	sections := make([]onepassword.ItemSection, 0, len(sourceItem.Sections))
+	for _, section := range sourceItem.Sections {
+		sectionIDs[section.ID] = uuid.New().String()
+		sections = append(sections, onepassword.ItemSection{
+			ID:    sectionIDs[section.ID],
+			Title: section.Title,
+		})
+	}
+
+	fields := make([]onepassword.ItemField, 0, len(sourceItem.Fields))
+	for _, field := range sourceItem.Fields {
+		field.ID = uuid.New().String()
+		if field.SectionID != nil {
+			sectionID := sectionIDs[*field.SectionID]
+			field.SectionID = &sectionID
+		}
+		fields = append(fields, field)
+	}
+
+	notes := sourceItem.Notes
+
+	return onepassword.ItemCreateParams{
+		Title:    itemName,
+		Category: sourceItem.Category,
+		VaultID:  vault.ID,
+		Sections: sections,
+		Fields:   fields,
+		Notes:    &notes,
+		Tags:     slices.Clone(sourceItem.Tags),
+		Websites: slices.Clone(sourceItem.Websites),
+	}
+}
+
+// PlanClone builds the parameters to clone the item, failing if an item with that name already exists in the vault.
+// A dry run uses it to fail in the same way the clone would.
+func PlanClone(
+	ctx context.Context,
+	client *onepassword.Client,
+	sourceItem *onepassword.Item,
+	vault *onepassword.VaultOverview,
+	itemName string,
+) (onepassword.ItemCreateParams, error) {
+	existing, err := FindItemWithName(ctx, client, vault, itemName)
+	if err != nil {
+		return onepassword.ItemCreateParams{}, err
+	}
+
+	if existing != nil {
+		return onepassword.ItemCreateParams{}, fmt.Errorf("item %s already exists in vault %s", itemName, vault.Title)
+	}
+
+	return CloneParams(sourceItem, vault, itemName), nil
+}
+
+// CloneItem copies the whole item into a new item in the vault, failing if an item with that name already exists there
+func CloneItem(
+	ctx context.Context,
+	client *onepassword.Client,
+	sourceItem *onepassword.Item,
+	vault *onepassword.VaultOverview,
+	itemName string,
+) (*onepassword.Item, error) {
+	params, err := PlanClone(ctx, client, sourceItem, vault, itemName)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := client.Items().Create(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
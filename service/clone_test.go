@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+func TestCloneItem(t *testing.T) {
+	ctx := t.Context()
+	client, store := NewMemoryClient("vault")
+	other := store.AddVault("other")
+
+	source := newItemWithSection(t, ctx, client, "source", "production", map[string]any{"A": "1"})
+	environment := map[string]any{"B": "2"}
+	source, err := UpdateItem(ctx, client, source, "staging", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := CloneItem(ctx, client, source, &other, "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := FindItemWithName(ctx, client, &other, "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.ID != clone.ID || found.Category != source.Category {
+		t.Fatalf("Expected the clone in the other vault, got %v", found)
+	}
+
+	if values := sectionValues(found, "production"); values["A"] != "1" {
+		t.Errorf("Expected A=1 in production, got %v", values)
+	}
+	if values := sectionValues(found, "staging"); values["B"] != "2" {
+		t.Errorf("Expected B=2 in staging, got %v", values)
+	}
+
+	for _, section := range found.Sections {
+		if FindSection(source, section.Title).ID == section.ID {
+			t.Errorf("Expected section %s to get a new id", section.Title)
+		}
+	}
+}
+
+func TestCloneItemExists(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	source := newItemWithSection(t, ctx, client, "source", "production", map[string]any{"A": "1"})
+	newItemWithSection(t, ctx, client, "clone", "production", map[string]any{"A": "0"})
+
+	vault := &onepassword.VaultOverview{ID: source.VaultID, Title: "vault"}
+
+	if _, err := PlanClone(ctx, client, source, vault, "clone"); err == nil {
+		t.Errorf("Expected planning a clone onto an existing item to fail")
+	}
+
+	if _, err := CloneItem(ctx, client, source, vault, "clone"); err == nil {
+		t.Errorf("Expected cloning onto an existing item to fail")
+	}
+
+	if _, err := PlanClone(ctx, client, source, vault, "new"); err != nil {
+		t.Errorf("Expected planning a clone to a new item to succeed, got %v", err)
+	}
+}
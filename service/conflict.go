@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// LockTimeout is how long a write keeps re-reading the item and applying its change again when
+// the item was changed by someone else in the meantime. Zero fails with a ConflictError straight away.
+var LockTimeout time.Duration
+
+// ConflictError is returned when an item changed between being read and being written
+type ConflictError struct {
+	Title    string
+	Expected uint32
+	Actual   uint32
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf(
+		"item %s was changed by someone else while it was being updated (version %d, expected %d), retry or set --lock-timeout",
+		e.Title,
+		e.Actual,
+		e.Expected,
+	)
+}
+
+// storedItemKey carries the stored copy of an item from the version check to the Put
+type storedItemKey struct{}
+
+// withStoredItem passes the stored copy of the item down to the Put, so wrappers like the snapshots don't read it again
+func withStoredItem(ctx context.Context, item onepassword.Item) context.Context {
+	return context.WithValue(ctx, storedItemKey{}, item)
+}
+
+// storedItem returns the stored copy of the item passed down by withStoredItem, if there is one
+func storedItem(ctx context.Context, vaultID string, itemID string) (onepassword.Item, bool) {
+	item, ok := ctx.Value(storedItemKey{}).(onepassword.Item)
+	return item, ok && item.VaultID == vaultID && item.ID == itemID
+}
+
+// putItem writes the item, provided the stored item is still at the version the item was read at. The api has
+// no conditional write, so this only narrows the window, a write landing between the Get and the Put is lost.
+func putItem(ctx context.Context, client *onepassword.Client, item onepassword.Item) (onepassword.Item, error) {
+	current, err := client.Items().Get(ctx, item.VaultID, item.ID)
+	if err != nil {
+		return onepassword.Item{}, err
+	}
+
+	if current.Version != item.Version {
+		return onepassword.Item{}, &ConflictError{Title: item.Title, Expected: item.Version, Actual: current.Version}
+	}
+
+	return client.Items().Put(withStoredItem(ctx, current), item)
+}
+
+// writeItem applies the change to the item and writes it. On a conflict the item is read again,
+// and the change applied to the fresh copy, until LockTimeout has passed.
+func writeItem(
+	ctx context.Context,
+	client *onepassword.Client,
+	item *onepassword.Item,
+	change func(item *onepassword.Item) error,
+) (*onepassword.Item, error) {
+	deadline := time.Now().Add(LockTimeout)
+	delay := 100 * time.Millisecond
+
+	for {
+		if err := change(item); err != nil {
+			return nil, err
+		}
+
+		updatedItem, err := putItem(ctx, client, *item)
+		if err == nil {
+			*item = updatedItem
+			return &updatedItem, nil
+		}
+
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || time.Now().Add(delay).After(deadline) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 2*time.Second)
+
+		refreshedItem, err := client.Items().Get(ctx, item.VaultID, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		*item = refreshedItem
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUpdateItemConflict(t *testing.T) {
+	ctx := t.Context()
+	client, store := NewMemoryClient("vault")
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+
+	item, err := CreateItem(ctx, client, vault, "item", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// someone else changes the item after we read it
+	store.Touch(item.ID)
+
+	LockTimeout = 0
+	environment := map[string]any{"A": "1"}
+	_, err = UpdateItem(ctx, client, item, "staging", &environment)
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+}
+
+func TestUpdateItemConflictRetry(t *testing.T) {
+	ctx := t.Context()
+	client, store := NewMemoryClient("vault")
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+
+	item, err := CreateItem(ctx, client, vault, "item", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := map[string]any{"A": "1"}
+	stale := *item
+	_, err = UpdateItem(ctx, client, item, "staging", &first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Touch(item.ID)
+
+	// the stale copy should be re-read and merged, keeping A
+	LockTimeout = time.Second
+	defer func() { LockTimeout = 0 }()
+
+	second := map[string]any{"B": "2"}
+	updated, err := UpdateItem(ctx, client, &stale, "staging", &second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := make(map[string]bool)
+	for _, field := range updated.Fields {
+		titles[field.Title] = true
+	}
+
+	if !titles["A"] || !titles["B"] {
+		t.Errorf("Expected fields A and B after the merge, got %v", updated.Fields)
+	}
+}
+
+func TestCopySectionKeepsSource(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+
+	source, _ := CreateItem(ctx, client, vault, "source", "staging")
+	environment := map[string]any{"A": "1"}
+	source, err := UpdateItem(ctx, client, source, "staging", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destination, _ := CreateItem(ctx, client, vault, "destination", "production")
+	err = CopySection(ctx, client, source, "staging", destination, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section := FindSection(source, "staging")
+	for _, field := range source.Fields {
+		if field.Title == "A" && *field.SectionID != section.ID {
+			t.Errorf("Expected the source field to stay in its section")
+		}
+	}
+
+	if FindSection(destination, "production") == nil || len(destination.Fields) != 1 {
+		t.Errorf("Expected the field to be copied to the destination, got %v", destination.Fields)
+	}
+}
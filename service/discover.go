@@ -0,0 +1,295 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/1password/onepassword-sdk-go"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultEnv is what {env} is for files that are not environment specific, like .env or config.json
+const DefaultEnv = "default"
+
+// skippedDirs are never searched for files to import, nor are directories starting with a dot
+var skippedDirs = []string{"node_modules", "vendor"}
+
+// skippedFiles are JSON files that are never configuration, even when they match the JSON glob
+var skippedFiles = []string{"package.json", "package-lock.json", "composer.json", "composer.lock", "tsconfig.json", "jsconfig.json"}
+
+// skippedEnvs are templates and examples rather than environments, eg .env.example or config.sample.json
+var skippedEnvs = []string{"local", "dist", "example", "sample"}
+
+// DefaultJSONFiles are the names of the JSON files that are discovered unless a JSON glob is given, with or
+// without an environment, eg config.json and config.production.json. Any other JSON file is too likely to be
+// tooling or test fixtures.
+var DefaultJSONFiles = []string{"config", "settings", "secrets", "appsettings"}
+
+// ImportTarget is a cascade of files to import into a section
+type ImportTarget struct {
+	Item    string
+	Section string
+	Format  string
+	// Path is the base file of the cascade, eg .env or config.json, read for EnvName
+	Path    string
+	EnvName string
+}
+
+// discoveredFile is a file found by DiscoverImports, before the patterns are applied
+type discoveredFile struct {
+	dir     string
+	format  string
+	path    string
+	envName string
+}
+
+// discoverFile works out the format, base file and environment of a file from its name. Files are found by
+// their environment specific files, eg .env.<env> or config.<env>.json, and local overrides are left to the
+// cascade. JSON files must match the glob, or have one of the DefaultJSONFiles names when it is empty.
+func discoverFile(dir string, name string, jsonGlob string) (discoveredFile, bool) {
+	if name == ".env" {
+		return discoveredFile{dir: dir, format: "env", path: filepath.Join(dir, ".env")}, true
+	}
+
+	if envName, ok := strings.CutPrefix(name, ".env."); ok {
+		if slices.Contains(skippedEnvs, envName) || strings.Contains(envName, ".") {
+			return discoveredFile{}, false
+		}
+		return discoveredFile{dir: dir, format: "env", path: filepath.Join(dir, ".env"), envName: envName}, true
+	}
+
+	// dotfiles are tool configuration, eg .eslintrc.json
+	if strings.HasPrefix(name, ".") || slices.Contains(skippedFiles, name) {
+		return discoveredFile{}, false
+	}
+
+	format := ""
+	switch filepath.Ext(name) {
+	case ".json":
+		format = "json"
+	case ".tfvars":
+		format = "tfvars"
+	default:
+		return discoveredFile{}, false
+	}
+
+	// config.json is the base of config.production.json, and config.local.json is part of their cascade
+	extension := filepath.Ext(name)
+	parts := strings.Split(strings.TrimSuffix(name, extension), ".")
+	if len(parts) > 2 || (len(parts) == 2 && slices.Contains(skippedEnvs, parts[1])) {
+		return discoveredFile{}, false
+	}
+
+	if format == "json" {
+		if jsonGlob != "" {
+			if matched, _ := filepath.Match(jsonGlob, name); !matched {
+				return discoveredFile{}, false
+			}
+		} else if !slices.Contains(DefaultJSONFiles, parts[0]) {
+			return discoveredFile{}, false
+		}
+	}
+
+	if len(parts) == 1 {
+		return discoveredFile{dir: dir, format: format, path: filepath.Join(dir, name)}, true
+	}
+	return discoveredFile{dir: dir, format: format, path: filepath.Join(dir, parts[0]+extension), envName: parts[1]}, true
+}
+
+// DiscoverImports finds the env, json and tfvars files under root, and maps them to items and sections with
+// the patterns. {dir} is the name of the directory the file is in, {path} its path from root with / replaced
+// by -, and {env} the environment from the file name, or DefaultEnv. JSON files are only discovered when
+// their name matches jsonGlob, eg "*.json", or one of DefaultJSONFiles when jsonGlob is empty.
+func DiscoverImports(root string, itemPattern string, sectionPattern string, jsonGlob string) ([]ImportTarget, error) {
+	if _, err := filepath.Match(jsonGlob, ""); err != nil {
+		return nil, fmt.Errorf("invalid json glob %s: %w", jsonGlob, err)
+	}
+
+	var files []discoveredFile
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != root && (strings.HasPrefix(entry.Name(), ".") || slices.Contains(skippedDirs, entry.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if file, ok := discoverFile(filepath.Dir(path), entry.Name(), jsonGlob); ok {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]ImportTarget, 0, len(files))
+	seen := make(map[string]ImportTarget)
+	for _, file := range files {
+		relative, err := filepath.Rel(root, file.dir)
+		if err != nil {
+			return nil, err
+		}
+
+		replacer := strings.NewReplacer(
+			"{dir}", filepath.Base(file.dir),
+			"{path}", strings.ReplaceAll(filepath.ToSlash(relative), "/", "-"),
+			"{env}", cmp.Or(file.envName, DefaultEnv),
+		)
+
+		target := ImportTarget{
+			Item:    replacer.Replace(itemPattern),
+			Section: replacer.Replace(sectionPattern),
+			Format:  file.format,
+			Path:    file.path,
+			EnvName: file.envName,
+		}
+
+		key := target.Item + "/" + target.Section
+		if other, ok := seen[key]; ok {
+			if other.Path == target.Path && other.EnvName == target.EnvName {
+				continue
+			}
+			return nil, fmt.Errorf("both %s and %s would be imported into %s", other.Path, target.Path, key)
+		}
+		seen[key] = target
+		targets = append(targets, target)
+	}
+
+	// a base file without environment specific files is imported on its own, otherwise it is part of their cascade
+	targets = slices.DeleteFunc(targets, func(target ImportTarget) bool {
+		if target.EnvName != "" {
+			return false
+		}
+		return slices.ContainsFunc(targets, func(other ImportTarget) bool {
+			return other.Path == target.Path && other.EnvName != ""
+		})
+	})
+
+	slices.SortFunc(targets, func(a ImportTarget, b ImportTarget) int {
+		return cmp.Or(strings.Compare(a.Item, b.Item), strings.Compare(a.Section, b.Section))
+	})
+	return targets, nil
+}
+
+// ImportResult is what happened to one target of a discovered import
+type ImportResult struct {
+	Target ImportTarget
+	Keys   int
+	Err    error
+}
+
+// DiscoveredImport imports the targets found by DiscoverImports
+type DiscoveredImport struct {
+	Targets []ImportTarget
+	// Cascade is the order env files are read in
+	Cascade Cascade
+	// Validate is called with the environment name and the environment of each target before it is saved, if set
+	Validate func(envName string, env map[string]any) error
+}
+
+// Run imports the targets into the vault. Items are imported Workers at a time, and the sections of one
+// item one after the other so they don't conflict. Failures are recorded in the results.
+func (d DiscoveredImport) Run(ctx context.Context, client *onepassword.Client, vault *onepassword.VaultOverview) []ImportResult {
+	byItem := make(map[string][]ImportTarget)
+	var itemNames []string
+	for _, target := range d.Targets {
+		if _, ok := byItem[target.Item]; !ok {
+			itemNames = append(itemNames, target.Item)
+		}
+		byItem[target.Item] = append(byItem[target.Item], target)
+	}
+
+	var mu sync.Mutex
+	var results []ImportResult
+
+	var group errgroup.Group
+	group.SetLimit(Workers)
+
+	for _, itemName := range itemNames {
+		group.Go(func() error {
+			itemResults := d.importItem(ctx, client, vault, itemName, byItem[itemName])
+
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, itemResults...)
+			return nil
+		})
+	}
+	group.Wait()
+
+	slices.SortFunc(results, func(a ImportResult, b ImportResult) int {
+		return cmp.Or(strings.Compare(a.Target.Item, b.Target.Item), strings.Compare(a.Target.Section, b.Target.Section))
+	})
+	return results
+}
+
+// importItem imports the sections of one item, creating it if needed. A file that fails doesn't stop the others.
+func (d DiscoveredImport) importItem(
+	ctx context.Context,
+	client *onepassword.Client,
+	vault *onepassword.VaultOverview,
+	itemName string,
+	targets []ImportTarget,
+) []ImportResult {
+	results := make([]ImportResult, 0, len(targets))
+
+	item, err := FindItemWithName(ctx, client, vault, itemName)
+	for _, target := range targets {
+		result := ImportResult{Target: target, Err: err}
+		if err == nil {
+			item, result.Keys, result.Err = d.importTarget(ctx, client, vault, item, target)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// importTarget reads the target and saves it to its section, creating the item if it is nil
+func (d DiscoveredImport) importTarget(
+	ctx context.Context,
+	client *onepassword.Client,
+	vault *onepassword.VaultOverview,
+	item *onepassword.Item,
+	target ImportTarget,
+) (*onepassword.Item, int, error) {
+	env, err := ReadFormat(target.Format, d.Cascade, target.EnvName, target.Path)
+	if err != nil {
+		return item, 0, err
+	}
+
+	if len(env) == 0 {
+		return item, 0, fmt.Errorf("no items found in %s", target.Path)
+	}
+
+	if d.Validate != nil {
+		err = d.Validate(cmp.Or(target.EnvName, target.Section), env)
+		if err != nil {
+			return item, len(env), err
+		}
+	}
+
+	if item == nil {
+		item, err = CreateItem(ctx, client, vault, target.Item, target.Section)
+		if err != nil {
+			return nil, len(env), err
+		}
+	}
+
+	updatedItem, err := UpdateItem(ctx, client, item, target.Section, &env)
+	if err != nil {
+		return item, len(env), err
+	}
+	return updatedItem, len(env), nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscoverImports(t *testing.T) {
+	ctx := t.Context()
+	root := t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		"api/.env":                  "A=1\n",
+		"api/.env.local":            "B=local\n",
+		"api/.env.production":       "B=2\n",
+		"worker/.env":               "C=3\n",
+		"web/config.json":           `{"D": 4, "E": {"F": 5, "G": 6}}`,
+		"web/config.staging.json":   `{"E": {"G": 7}}`,
+		"web/package.json":          `{"name": "web"}`,
+		"web/node_modules/x/.env":   "E=5\n",
+		"web/terraform.tfvars.orig": "F = 6\n",
+	})
+
+	targets, err := DiscoverImports(root, "svc-{dir}", "{env}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ImportTarget{
+		{Item: "svc-api", Section: "production", Format: "env", Path: filepath.Join(root, "api", ".env"), EnvName: "production"},
+		{Item: "svc-web", Section: "staging", Format: "json", Path: filepath.Join(root, "web", "config.json"), EnvName: "staging"},
+		{Item: "svc-worker", Section: DefaultEnv, Format: "env", Path: filepath.Join(root, "worker", ".env")},
+	}
+
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %v", len(expected), targets)
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], targets[i])
+		}
+	}
+
+	client, _ := NewMemoryClient("vault")
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+
+	for _, result := range (DiscoveredImport{Targets: targets, Cascade: DefaultCascade}).Run(ctx, client, vault) {
+		if result.Err != nil {
+			t.Errorf("Expected %s to be imported, got %v", result.Target.Path, result.Err)
+		}
+	}
+
+	env, err := ReadOnePassword(ctx, client, "vault", "svc-api", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env["A"] != int64(1) || env["B"] != int64(2) {
+		t.Errorf("Expected the env cascade to be imported, got %v", env)
+	}
+
+	env, err = ReadOnePassword(ctx, client, "vault", "svc-web", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env["D"] != int64(4) || env["E"] == nil {
+		t.Errorf("Expected the json cascade to be imported, got %v", env)
+	}
+}
+
+func TestDiscoverImportsSkipsTooling(t *testing.T) {
+	root := t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		"api/.env":                       "A=1\n",
+		"api/.env.example":               "A=\n",
+		"api/.env.sample":                "A=\n",
+		"api/.eslintrc.json":             `{"rules": {}}`,
+		"api/tsconfig.build.json":        `{"extends": "./tsconfig.json"}`,
+		"api/testdata/fixture.json":      `{"id": 1}`,
+		"api/config.example.json":        `{"A": ""}`,
+		"api/.github/settings.json":      `{"A": 1}`,
+		"infra/terraform.tfvars":         "B = 2\n",
+		"infra/terraform.tfvars.example": "B = 0\n",
+	})
+
+	targets, err := DiscoverImports(root, "{dir}", "{env}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ImportTarget{
+		{Item: "api", Section: DefaultEnv, Format: "env", Path: filepath.Join(root, "api", ".env")},
+		{Item: "infra", Section: DefaultEnv, Format: "tfvars", Path: filepath.Join(root, "infra", "terraform.tfvars")},
+	}
+
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %v", len(expected), targets)
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], targets[i])
+		}
+	}
+}
+
+func TestDiscoverImportsJSONGlob(t *testing.T) {
+	root := t.TempDir()
+
+	writeFiles(t, root, map[string]string{
+		"api/app.json":            `{"A": 1}`,
+		"api/app.production.json": `{"A": 2}`,
+		"api/config.json":         `{"B": 1}`,
+		"api/package.json":        `{"name": "api"}`,
+	})
+
+	targets, err := DiscoverImports(root, "{dir}", "{env}", "app*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(targets) != 1 || targets[0].Path != filepath.Join(root, "api", "app.json") || targets[0].EnvName != "production" {
+		t.Errorf("Expected only app.production.json to be discovered, got %v", targets)
+	}
+
+	if _, err := DiscoverImports(root, "{dir}", "{env}", "["); err == nil {
+		t.Errorf("Expected an invalid glob to be refused")
+	}
+}
@@ -5,13 +5,17 @@ Copyright © 2025 John Lennard <john@yakmoo.se>
 package service
 
 import (
+	"cmp"
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
 
 	"github.com/hashicorp/go-envparse"
 )
 
-// parseFile wrapper around the file parser
+// parseFile adds the variables in the env file to env, an empty path reads stdin.
+// Files that don't exist are skipped, any other error is returned.
 func parseFile(path string, env *map[string]any) error {
 	var fh *os.File
 	var err error
@@ -19,14 +23,18 @@ func parseFile(path string, env *map[string]any) error {
 		fh = os.Stdin
 	} else {
 		fh, err = os.Open(path)
-		if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
+		if err != nil {
+			return err
+		}
 		defer fh.Close()
 	}
 
 	parsedEnvfile, err := envparse.Parse(fh)
 	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", cmp.Or(path, "stdin"), err)
 	}
 
 	for k, v := range parsedEnvfile {
@@ -35,36 +43,37 @@ func parseFile(path string, env *map[string]any) error {
 	return nil
 }
 
-// ReadEnv reads the environment file in .env format in the order .env.local, .env, .env.<environment>, .env.<environment>.local
-func ReadEnv(envName, path string) (map[string]any, error) {
-	// read the .environmentName file
-	// .env.local .env .env.<environmentName> .env.<environmentName>.local
-
-	var fileNames []string
-	if path == "" {
-		fileNames = []string{""}
-	} else {
-		fileNames = []string{
-			path,
-			path + ".local",
-		}
-
-		if envName != "" {
-			fileNames = append(
-				fileNames, path+"."+envName,
-				path+"."+envName+".local",
-			)
-		}
+// ReadEnvCascade reads the env files of the cascade in order, and returns which file each key came from.
+// An empty path reads stdin, without a cascade.
+func ReadEnvCascade(cascade Cascade, envName string, path string) (map[string]any, map[string]string, error) {
+	fileNames := []string{""}
+	if path != "" {
+		fileNames = cascade.Paths(envName, path)
 	}
-	env := make(map[string]any, 0)
+
+	env := make(map[string]any)
+	sources := make(map[string]string)
 	for _, fileName := range fileNames {
-		err := parseFile(fileName, &env)
+		fileEnv := make(map[string]any)
+		err := parseFile(fileName, &fileEnv)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		for k, v := range fileEnv {
+			env[k] = v
+			sources[k] = cmp.Or(fileName, "stdin")
 		}
 	}
 
-	return env, nil
+	return env, sources, nil
+}
+
+// ReadEnv reads the environment file in .env format with the default cascade, <path>, <path>.local,
+// <path>.<env> and <path>.<env>.local, skipping the files that don't exist
+func ReadEnv(envName, path string) (map[string]any, error) {
+	env, _, err := ReadEnvCascade(DefaultCascade, envName, path)
+	return env, err
 }
 
 // WriteEnv writes the environment file in .env format
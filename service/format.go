@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReadCascade reads the environment from local files in the given format with the cascade, and which file each
+// key came from. An empty path is stdin for env files.
+func ReadCascade(format string, cascade Cascade, envName string, path string) (map[string]any, map[string]string, error) {
+	switch format {
+	case "env":
+		return ReadEnvCascade(cascade, envName, path)
+	case "hcl", "tfvar", "tfvars":
+		return ReadStructuredCascade(cascade, envName, path, readHclFile)
+	case "json":
+		return ReadStructuredCascade(cascade, envName, path, readJsonFile)
+	}
+
+	return nil, nil, fmt.Errorf("unknown format %s, expected env, json or tfvars", format)
+}
+
+// ReadFormat reads the environment from local files in the given format, see ReadCascade
+func ReadFormat(format string, cascade Cascade, envName string, path string) (map[string]any, error) {
+	env, _, err := ReadCascade(format, cascade, envName, path)
+	return env, err
+}
+
+// WriteFormat writes the environment to the file in the given format, an empty file name is stdout
+func WriteFormat(fileName string, format string, env map[string]any) error {
+	switch format {
+	case "json":
+		return WriteJSON(fileName, env)
+	case "env":
+		return WriteEnv(fileName, env)
+	case "tfvars", "hcl", "tfvar":
+		return WriteHcl(fileName, env)
+	}
+
+	return fmt.Errorf("unknown format %s, expected env, json or tfvars", format)
+}
+
+// WriteAtomic writes the environment to a temporary file next to fileName and renames it into place,
+// so readers never see a partly written file. The file is only readable by the owner.
+func WriteAtomic(fileName string, format string, env map[string]any) error {
+	fh, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	tmpName := fh.Name()
+	fh.Close()
+
+	err = WriteFormat(tmpName, format, env)
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	err = os.Rename(tmpName, fileName)
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return nil
+}
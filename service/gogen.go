@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// goInitialisms are written in upper case in field names, as golint would
+var goInitialisms = []string{"API", "DB", "DNS", "HTML", "HTTP", "HTTPS", "ID", "IP", "JSON", "SQL", "SSH", "TLS", "TTL", "UI", "URI", "URL", "UUID", "XML"}
+
+// GoField is a field of the generated struct
+type GoField struct {
+	Name string
+	Key  string
+	Type string
+}
+
+// Tag is the struct tag of the field as a Go literal, keys can hold any character
+func (f GoField) Tag() string {
+	tag := "env:" + strconv.Quote(f.Key)
+	if strconv.CanBackquote(tag) {
+		return "`" + tag + "`"
+	}
+	return strconv.Quote(tag)
+}
+
+// goFieldName turns an environment key like API_BASE_URL into a field name like APIBaseURL
+func goFieldName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var name strings.Builder
+	for _, part := range parts {
+		upper := strings.ToUpper(part)
+		if slices.Contains(goInitialisms, upper) {
+			name.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(strings.ToLower(part))
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+
+	if name.Len() == 0 || !unicode.IsLetter([]rune(name.String())[0]) {
+		return "X" + name.String()
+	}
+	return name.String()
+}
+
+// goType infers the Go type of a value, strings are typed the same way as values from 1password
+func goType(value any) string {
+	if s, ok := value.(string); ok {
+		value = stringishToAny(s)
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return "bool"
+	case int, int64:
+		return "int64"
+	case float64:
+		if v == float64(int64(v)) {
+			return "int64"
+		}
+		return "float64"
+	case []any:
+		return "[]any"
+	case map[string]any:
+		return "map[string]any"
+	}
+	return "string"
+}
+
+// GoFields infers the struct fields for the environment, sorted by key
+func GoFields(env map[string]any) []GoField {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	fields := make([]GoField, 0, len(keys))
+	names := make(map[string]int)
+	for _, key := range keys {
+		name := goFieldName(key)
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, names[name])
+		}
+
+		fields = append(fields, GoField{Name: name, Key: key, Type: goType(env[key])})
+	}
+
+	return fields
+}
+
+var goConfigTemplate = template.Must(template.New("config").Parse(`// Code generated by envop gen go. DO NOT EDIT.
+
+package {{ .Package }}
+{{- if .Imports }}
+
+import (
+{{- range .Imports }}
+	{{ printf "%q" . }}
+{{- end }}
+)
+{{- end }}
+
+// {{ .Type }} is the configuration read from the environment
+type {{ .Type }} struct {
+{{- range .Fields }}
+	{{ .Name }} {{ .Type }} {{ .Tag }}
+{{- end }}
+}
+
+// Load reads the {{ .Type }} from the environment, every key must be set
+func Load() (*{{ .Type }}, error) {
+	var config {{ .Type }}
+{{- range .Fields }}
+
+	if value, ok := os.LookupEnv({{ printf "%q" .Key }}); !ok {
+		return nil, fmt.Errorf("%s is not set", {{ printf "%q" .Key }})
+{{- if eq .Type "string" }}
+	} else {
+		config.{{ .Name }} = value
+	}
+{{- else if eq .Type "int64" }}
+	} else if parsed, err := strconv.ParseInt(value, 10, 64); err != nil {
+		return nil, fmt.Errorf("%s: %w", {{ printf "%q" .Key }}, err)
+	} else {
+		config.{{ .Name }} = parsed
+	}
+{{- else if eq .Type "float64" }}
+	} else if parsed, err := strconv.ParseFloat(value, 64); err != nil {
+		return nil, fmt.Errorf("%s: %w", {{ printf "%q" .Key }}, err)
+	} else {
+		config.{{ .Name }} = parsed
+	}
+{{- else if eq .Type "bool" }}
+	} else if parsed, err := strconv.ParseBool(value); err != nil {
+		return nil, fmt.Errorf("%s: %w", {{ printf "%q" .Key }}, err)
+	} else {
+		config.{{ .Name }} = parsed
+	}
+{{- else }}
+	} else if err := json.Unmarshal([]byte(value), &config.{{ .Name }}); err != nil {
+		return nil, fmt.Errorf("%s: %w", {{ printf "%q" .Key }}, err)
+	}
+{{- end }}
+{{- end }}
+
+	return &config, nil
+}
+`))
+
+// GenerateGoConfig generates a Go file with a struct for the environment and a Load function to read it
+func GenerateGoConfig(packageName string, typeName string, env map[string]any) ([]byte, error) {
+	fields := GoFields(env)
+
+	// only import what is used, or the generated file won't compile
+	imports := make([]string, 0, 4)
+	if len(fields) > 0 {
+		imports = append(imports, "fmt", "os")
+	}
+	for _, field := range fields {
+		switch field.Type {
+		case "int64", "float64", "bool":
+			imports = append(imports, "strconv")
+		case "[]any", "map[string]any":
+			imports = append(imports, "encoding/json")
+		}
+	}
+	slices.Sort(imports)
+	imports = slices.Compact(imports)
+
+	var out bytes.Buffer
+	err := goConfigTemplate.Execute(&out, map[string]any{
+		"Package": packageName,
+		"Type":    typeName,
+		"Fields":  fields,
+		"Imports": imports,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(out.Bytes())
+}
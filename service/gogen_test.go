@@ -0,0 +1,98 @@
+package service
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// sourceImporter type checks the standard library from source once, for every generated file
+var sourceImporter = importer.ForCompiler(token.NewFileSet(), "source", nil)
+
+// typeCheck fails the test unless the generated source compiles, which catches unused imports and bad literals
+func typeCheck(t *testing.T, source []byte) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "config.go", source, parser.AllErrors)
+	if err != nil {
+		t.Fatalf("Expected the generated code to parse, got %v\n%s", err, source)
+	}
+
+	config := types.Config{Importer: sourceImporter}
+	_, err = config.Check("config", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("Expected the generated code to compile, got %v\n%s", err, source)
+	}
+}
+
+func TestGoFieldName(t *testing.T) {
+	names := map[string]string{
+		"API_BASE_URL": "APIBaseURL",
+		"port":         "Port",
+		"db.host-name": "DBHostName",
+		"2FA_SECRET":   "X2faSecret",
+	}
+
+	for key, expected := range names {
+		if name := goFieldName(key); name != expected {
+			t.Errorf("Expected %s to be %s, got %s", key, expected, name)
+		}
+	}
+}
+
+func TestGenerateGoConfig(t *testing.T) {
+	env := map[string]any{
+		"PORT":     "8080",
+		"DEBUG":    "true",
+		"RATIO":    "0.5",
+		"API_URL":  "https://example.com",
+		"FEATURES": `["a", "b"]`,
+	}
+
+	out, err := GenerateGoConfig("config", "Config", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheck(t, out)
+
+	// ignore the alignment gofmt adds
+	source := strings.Join(strings.Fields(string(out)), " ")
+	for _, expected := range []string{
+		"package config",
+		"APIURL string `env:\"API_URL\"`",
+		"Debug bool `env:\"DEBUG\"`",
+		"Features []any `env:\"FEATURES\"`",
+		"Port int64 `env:\"PORT\"`",
+		"Ratio float64 `env:\"RATIO\"`",
+		"func Load() (*Config, error) {",
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("Expected the generated code to contain %q, got\n%s", expected, source)
+		}
+	}
+}
+
+func TestGenerateGoConfigCompiles(t *testing.T) {
+	envs := map[string]map[string]any{
+		"empty":       {},
+		"strings":     {"NAME": "api"},
+		"odd keys":    {`QUOTE"KEY`: "a", "BACK`TICK": "b", "PERCENT%d": "1", `BACK\SLASH`: "true"},
+		"json only":   {"FEATURES": `["a"]`},
+		"numbers too": {"PORT": "8080", "RATIO": "0.5"},
+	}
+
+	for name, env := range envs {
+		t.Run(name, func(t *testing.T) {
+			out, err := GenerateGoConfig("config", "Config", env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			typeCheck(t, out)
+		})
+	}
+}
@@ -6,7 +6,14 @@ import (
 	"github.com/genelet/horizon/dethcl"
 )
 
-func ReadHcl(_, path string) (map[string]any, error) {
+// ReadHcl reads the tfvars file with the default cascade, eg terraform.tfvars then terraform.<env>.tfvars, merging nested objects
+func ReadHcl(envName, path string) (map[string]any, error) {
+	env, _, err := ReadStructuredCascade(DefaultCascade, envName, path, readHclFile)
+	return env, err
+}
+
+// readHclFile reads a single tfvars file
+func readHclFile(path string) (map[string]any, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -5,8 +5,14 @@ import (
 	"os"
 )
 
-func ReadJson(_, path string) (map[string]any, error) {
+// ReadJson reads the JSON file with the default cascade, eg config.json then config.<env>.json, merging nested objects
+func ReadJson(envName, path string) (map[string]any, error) {
+	env, _, err := ReadStructuredCascade(DefaultCascade, envName, path, readJsonFile)
+	return env, err
+}
 
+// readJsonFile reads a single JSON file
+func readJsonFile(path string) (map[string]any, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
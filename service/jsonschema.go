@@ -0,0 +1,175 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+)
+
+// JSONSchemaDraft is the JSON Schema version of the generated schemas
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateJSONSchema describes the environment as a JSON Schema object, with every key required.
+// The environment is expected to be typed by stringishToAny, as ReadOnePassword does.
+func GenerateJSONSchema(env map[string]any) map[string]any {
+	schema := jsonSchemaFor(env)
+	schema["$schema"] = JSONSchemaDraft
+	return schema
+}
+
+// jsonSchemaFor infers the schema for a single value
+func jsonSchemaFor(value any) map[string]any {
+	switch v := value.(type) {
+	case nil:
+		return map[string]any{"type": "null"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	case int, int64:
+		return map[string]any{"type": "integer"}
+	case float64:
+		if v == float64(int64(v)) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case string:
+		schema := map[string]any{"type": "string"}
+		if parsed, err := url.Parse(v); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+			schema["format"] = "uri"
+		}
+		return schema
+	case []any:
+		schema := map[string]any{"type": "array"}
+		if len(v) > 0 {
+			schema["items"] = jsonSchemaFor(v[0])
+		}
+		return schema
+	case map[string]any:
+		properties := make(map[string]any, len(v))
+		required := make([]string, 0, len(v))
+		for key, property := range v {
+			properties[key] = jsonSchemaFor(property)
+			required = append(required, key)
+		}
+		slices.Sort(required)
+
+		return map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	}
+
+	return map[string]any{}
+}
+
+// MergeJSONSchema adds the generated properties to an existing schema. Properties that are already
+// described keep their existing schema, so hand written descriptions and constraints survive, and
+// nothing is removed. The required keys are the union of both.
+func MergeJSONSchema(existing map[string]any, generated map[string]any) (map[string]any, error) {
+	merged := make(map[string]any, len(existing))
+	for k, v := range existing {
+		merged[k] = v
+	}
+
+	for _, k := range []string{"$schema", "type"} {
+		if _, ok := merged[k]; !ok {
+			merged[k] = generated[k]
+		}
+	}
+
+	properties := make(map[string]any)
+	if existingProperties, ok := existing["properties"].(map[string]any); ok {
+		for k, v := range existingProperties {
+			properties[k] = v
+		}
+	}
+	if generatedProperties, ok := generated["properties"].(map[string]any); ok {
+		for k, v := range generatedProperties {
+			if _, ok := properties[k]; !ok {
+				properties[k] = v
+			}
+		}
+	}
+	merged["properties"] = properties
+
+	required := make([]string, 0)
+	for _, schema := range []map[string]any{existing, generated} {
+		keys, err := requiredKeys(schema)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if !slices.Contains(required, key) {
+				required = append(required, key)
+			}
+		}
+	}
+	slices.Sort(required)
+	merged["required"] = required
+
+	return merged, nil
+}
+
+// requiredKeys reads the required keys of a schema, which may have been edited by hand
+func requiredKeys(schema map[string]any) ([]string, error) {
+	switch keys := schema["required"].(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return keys, nil
+	case []any:
+		required := make([]string, 0, len(keys))
+		for _, key := range keys {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("required must be a list of property names, found %v", key)
+			}
+			required = append(required, name)
+		}
+		return required, nil
+	default:
+		return nil, fmt.Errorf("required must be a list of property names, found %v", keys)
+	}
+}
+
+// ReadJSONSchema reads an existing schema, returning nil if the file does not exist
+func ReadJSONSchema(path string) (map[string]any, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schema := map[string]any{}
+	err = json.Unmarshal(raw, &schema)
+	return schema, err
+}
+
+// WriteJSONSchema writes the schema as indented JSON
+func WriteJSONSchema(fileName string, schema map[string]any) error {
+	var fh *os.File
+	var err error
+
+	if fileName == "" {
+		fh = os.Stdout
+	} else {
+		fh, err = os.Create(fileName)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fh.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestGenerateJSONSchema(t *testing.T) {
+	schema := GenerateJSONSchema(map[string]any{
+		"NAME":     "api",
+		"PORT":     int64(8080),
+		"RATIO":    0.5,
+		"DEBUG":    true,
+		"API_URL":  "https://example.com",
+		"FEATURES": []any{"a"},
+		"EMPTY":    nil,
+	})
+
+	if schema["$schema"] != JSONSchemaDraft || schema["type"] != "object" {
+		t.Errorf("Expected an object schema, got %v", schema)
+	}
+
+	expected := map[string]any{
+		"NAME":     map[string]any{"type": "string"},
+		"PORT":     map[string]any{"type": "integer"},
+		"RATIO":    map[string]any{"type": "number"},
+		"DEBUG":    map[string]any{"type": "boolean"},
+		"API_URL":  map[string]any{"type": "string", "format": "uri"},
+		"FEATURES": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"EMPTY":    map[string]any{"type": "null"},
+	}
+	if !reflect.DeepEqual(schema["properties"], expected) {
+		t.Errorf("Expected properties %v, got %v", expected, schema["properties"])
+	}
+
+	required := []string{"API_URL", "DEBUG", "EMPTY", "FEATURES", "NAME", "PORT", "RATIO"}
+	if !slices.Equal(schema["required"].([]string), required) {
+		t.Errorf("Expected every key to be required in order, got %v", schema["required"])
+	}
+}
+
+func TestMergeJSONSchema(t *testing.T) {
+	// as read from a file
+	existing := map[string]any{
+		"$schema": JSONSchemaDraft,
+		"type":    "object",
+		"properties": map[string]any{
+			"PORT": map[string]any{"type": "integer", "description": "the port to listen on", "minimum": float64(1)},
+			"OLD":  map[string]any{"type": "string"},
+		},
+		"required": []any{"PORT", "OLD"},
+	}
+
+	generated := GenerateJSONSchema(map[string]any{"PORT": int64(8080), "NAME": "api"})
+
+	merged, err := MergeJSONSchema(existing, generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties := merged["properties"].(map[string]any)
+	if properties["PORT"].(map[string]any)["description"] != "the port to listen on" {
+		t.Errorf("Expected the hand written PORT schema to be kept, got %v", properties["PORT"])
+	}
+	if properties["OLD"] == nil || properties["NAME"] == nil {
+		t.Errorf("Expected OLD to be kept and NAME to be added, got %v", properties)
+	}
+
+	required := []string{"NAME", "OLD", "PORT"}
+	if !slices.Equal(merged["required"].([]string), required) {
+		t.Errorf("Expected the required keys %v, got %v", required, merged["required"])
+	}
+}
+
+func TestMergeJSONSchemaInvalidRequired(t *testing.T) {
+	generated := GenerateJSONSchema(map[string]any{"PORT": int64(8080)})
+
+	for _, required := range []any{[]any{"PORT", float64(1)}, "PORT"} {
+		existing := map[string]any{"type": "object", "required": required}
+
+		if _, err := MergeJSONSchema(existing, generated); err == nil {
+			t.Errorf("Expected required %v to be refused", required)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// MatrixColumn is one section of one item in a key matrix
+type MatrixColumn struct {
+	Item    string
+	Section string
+}
+
+// MatrixCell is the state of a key in a column. Cells in the same row with the same value share
+// a group, so values can be compared without being shown. Missing and empty cells have group 0.
+type MatrixCell struct {
+	State string
+	Group int
+}
+
+// KeyMatrix shows which keys are present, missing or empty across sections
+type KeyMatrix struct {
+	Columns []MatrixColumn
+	Keys    []string
+	Cells   map[string][]MatrixCell
+}
+
+// BuildKeyMatrix builds the matrix for the named sections of the items, or all their sections if none are named
+func BuildKeyMatrix(items []*onepassword.Item, sectionNames []string) *KeyMatrix {
+	matrix := &KeyMatrix{Cells: make(map[string][]MatrixCell)}
+
+	values := make([]map[string]string, 0)
+	for _, item := range items {
+		names := sectionNames
+		if len(names) == 0 {
+			for _, section := range item.Sections {
+				names = append(names, section.Title)
+			}
+		}
+
+		for _, name := range names {
+			matrix.Columns = append(matrix.Columns, MatrixColumn{Item: item.Title, Section: name})
+
+			column := make(map[string]string)
+			for title, field := range sectionFields(item, name) {
+				column[title] = field.Value
+				if _, ok := matrix.Cells[title]; !ok {
+					matrix.Keys = append(matrix.Keys, title)
+					matrix.Cells[title] = nil
+				}
+			}
+			values = append(values, column)
+		}
+	}
+
+	slices.Sort(matrix.Keys)
+
+	for _, key := range matrix.Keys {
+		groups := make(map[string]int)
+		cells := make([]MatrixCell, 0, len(values))
+
+		for _, column := range values {
+			value, ok := column[key]
+			switch {
+			case !ok:
+				cells = append(cells, MatrixCell{State: "missing"})
+			case strings.TrimSpace(value) == "":
+				cells = append(cells, MatrixCell{State: "empty"})
+			default:
+				if _, ok := groups[value]; !ok {
+					groups[value] = len(groups) + 1
+				}
+				cells = append(cells, MatrixCell{State: "present", Group: groups[value]})
+			}
+		}
+
+		matrix.Cells[key] = cells
+	}
+
+	return matrix
+}
+
+// Missing counts the cells where a key is missing
+func (m *KeyMatrix) Missing() int {
+	missing := 0
+	for _, cells := range m.Cells {
+		for _, cell := range cells {
+			if cell.State == "missing" {
+				missing++
+			}
+		}
+	}
+	return missing
+}
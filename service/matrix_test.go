@@ -0,0 +1,65 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+func TestBuildKeyMatrix(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	api := newItemWithSection(t, ctx, client, "api", "production", map[string]any{"A": "1", "B": ""})
+	environment := map[string]any{"A": "1", "C": "3"}
+	api, err := UpdateItem(ctx, client, api, "staging", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worker := newItemWithSection(t, ctx, client, "worker", "production", map[string]any{"A": "2"})
+
+	t.Run("all sections", func(t *testing.T) {
+		matrix := BuildKeyMatrix([]*onepassword.Item{api, worker}, nil)
+
+		columns := []MatrixColumn{{"api", "production"}, {"api", "staging"}, {"worker", "production"}}
+		if !slices.Equal(matrix.Columns, columns) {
+			t.Errorf("Expected columns %v, got %v", columns, matrix.Columns)
+		}
+
+		if !slices.Equal(matrix.Keys, []string{"A", "B", "C"}) {
+			t.Errorf("Expected keys A B C, got %v", matrix.Keys)
+		}
+
+		cells := map[string][]MatrixCell{
+			// the same value shares a group, without showing it
+			"A": {{"present", 1}, {"present", 1}, {"present", 2}},
+			"B": {{"empty", 0}, {"missing", 0}, {"missing", 0}},
+			"C": {{"missing", 0}, {"present", 1}, {"missing", 0}},
+		}
+		for key, expected := range cells {
+			if !slices.Equal(matrix.Cells[key], expected) {
+				t.Errorf("Expected %s to be %v, got %v", key, expected, matrix.Cells[key])
+			}
+		}
+
+		if missing := matrix.Missing(); missing != 4 {
+			t.Errorf("Expected 4 missing cells, got %d", missing)
+		}
+	})
+
+	t.Run("named sections", func(t *testing.T) {
+		matrix := BuildKeyMatrix([]*onepassword.Item{api, worker}, []string{"staging"})
+
+		columns := []MatrixColumn{{"api", "staging"}, {"worker", "staging"}}
+		if !slices.Equal(matrix.Columns, columns) {
+			t.Errorf("Expected columns %v, got %v", columns, matrix.Columns)
+		}
+
+		expected := []MatrixCell{{"present", 1}, {"missing", 0}}
+		if !slices.Equal(matrix.Cells["C"], expected) {
+			t.Errorf("Expected C to be missing from the worker, got %v", matrix.Cells["C"])
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/google/uuid"
+)
+
+// MemoryStore is an in-memory stand in for 1password, for tests and benchmarks
+type MemoryStore struct {
+	mu     sync.Mutex
+	vaults []onepassword.VaultOverview
+	items  map[string]onepassword.Item
+}
+
+// NewMemoryClient returns a client backed by a new in-memory store with the named vaults
+func NewMemoryClient(vaultNames ...string) (*onepassword.Client, *MemoryStore) {
+	store := &MemoryStore{items: make(map[string]onepassword.Item)}
+	for _, vaultName := range vaultNames {
+		store.AddVault(vaultName)
+	}
+
+	return &onepassword.Client{
+		ItemsAPI:  &memoryItems{store: store},
+		VaultsAPI: &memoryVaults{store: store},
+	}, store
+}
+
+// AddVault adds a vault to the store
+func (s *MemoryStore) AddVault(vaultName string) onepassword.VaultOverview {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vault := onepassword.VaultOverview{
+		ID:        uuid.New().String(),
+		Title:     vaultName,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	s.vaults = append(s.vaults, vault)
+	return vault
+}
+
+// Touch bumps the version of an item, as if someone else had changed it
+func (s *MemoryStore) Touch(itemID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.items[itemID]
+	item.Version++
+	s.items[itemID] = item
+}
+
+type memoryVaults struct {
+	store *MemoryStore
+}
+
+func (v *memoryVaults) List(_ context.Context) ([]onepassword.VaultOverview, error) {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+
+	return slices.Clone(v.store.vaults), nil
+}
+
+type memoryItems struct {
+	store *MemoryStore
+}
+
+func (i *memoryItems) Create(_ context.Context, params onepassword.ItemCreateParams) (onepassword.Item, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	item := onepassword.Item{
+		ID:        uuid.New().String(),
+		Title:     params.Title,
+		Category:  params.Category,
+		VaultID:   params.VaultID,
+		Fields:    slices.Clone(params.Fields),
+		Sections:  slices.Clone(params.Sections),
+		Tags:      slices.Clone(params.Tags),
+		Websites:  slices.Clone(params.Websites),
+		Version:   1,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if params.Notes != nil {
+		item.Notes = *params.Notes
+	}
+
+	i.store.items[item.ID] = item
+	return cloneItem(item), nil
+}
+
+func (i *memoryItems) Get(_ context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	item, ok := i.store.items[itemID]
+	if !ok || item.VaultID != vaultID {
+		return onepassword.Item{}, fmt.Errorf("item %s not found", itemID)
+	}
+	return cloneItem(item), nil
+}
+
+func (i *memoryItems) Put(_ context.Context, item onepassword.Item) (onepassword.Item, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	current, ok := i.store.items[item.ID]
+	if !ok || current.VaultID != item.VaultID {
+		return onepassword.Item{}, fmt.Errorf("item %s not found", item.ID)
+	}
+
+	item = cloneItem(item)
+	item.Version = current.Version + 1
+	item.UpdatedAt = time.Now()
+	i.store.items[item.ID] = item
+	return cloneItem(item), nil
+}
+
+func (i *memoryItems) Delete(_ context.Context, vaultID string, itemID string) error {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	item, ok := i.store.items[itemID]
+	if !ok || item.VaultID != vaultID {
+		return fmt.Errorf("item %s not found", itemID)
+	}
+	delete(i.store.items, itemID)
+	return nil
+}
+
+func (i *memoryItems) Archive(ctx context.Context, vaultID string, itemID string) error {
+	return i.Delete(ctx, vaultID, itemID)
+}
+
+func (i *memoryItems) List(_ context.Context, vaultID string, _ ...onepassword.ItemListFilter) ([]onepassword.ItemOverview, error) {
+	i.store.mu.Lock()
+	defer i.store.mu.Unlock()
+
+	overviews := make([]onepassword.ItemOverview, 0)
+	for _, item := range i.store.items {
+		if item.VaultID != vaultID {
+			continue
+		}
+		overviews = append(overviews, onepassword.ItemOverview{
+			ID:        item.ID,
+			Title:     item.Title,
+			Category:  item.Category,
+			VaultID:   item.VaultID,
+			Tags:      item.Tags,
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: item.UpdatedAt,
+			State:     onepassword.ItemStateActive,
+		})
+	}
+
+	slices.SortFunc(overviews, func(a onepassword.ItemOverview, b onepassword.ItemOverview) int {
+		return strings.Compare(a.Title, b.Title)
+	})
+	return overviews, nil
+}
+
+func (i *memoryItems) Shares() onepassword.ItemsSharesAPI {
+	return nil
+}
+
+func (i *memoryItems) Files() onepassword.ItemsFilesAPI {
+	return nil
+}
+
+// cloneItem copies an item deeply enough that the store and callers don't share field section ids
+func cloneItem(item onepassword.Item) onepassword.Item {
+	item.Sections = slices.Clone(item.Sections)
+	item.Tags = slices.Clone(item.Tags)
+	item.Websites = slices.Clone(item.Websites)
+	item.Fields = slices.Clone(item.Fields)
+	for i, field := range item.Fields {
+		if field.SectionID != nil {
+			sectionID := *field.SectionID
+			item.Fields[i].SectionID = &sectionID
+		}
+	}
+	return item
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// MoveError reports how far a move got before it stopped
+type MoveError struct {
+	Step string
+	Err  error
+	// Unchanged is set when nothing was moved, either because the copy failed or because it was rolled back
+	Unchanged bool
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("move stopped while %s: %v", e.Step, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+// MoveSection moves a section to another item. The copy is read back and verified before the source
+// section is removed, and if the source can't be removed the copy is rolled back. Moving within the
+// same item renames the section, or merges it into the destination section, in a single write.
+func MoveSection(
+	ctx context.Context,
+	client *onepassword.Client,
+	sourceItem *onepassword.Item,
+	sourceSectionName string,
+	destinationItem *onepassword.Item,
+	destinationSectionName string,
+) error {
+
+	if FindSection(sourceItem, sourceSectionName) == nil {
+		return fmt.Errorf("sourceSection %s not found in item %s", sourceSectionName, sourceItem.Title)
+	}
+
+	if sourceItem.VaultID == destinationItem.VaultID && sourceItem.ID == destinationItem.ID {
+		if sourceSectionName == destinationSectionName {
+			return fmt.Errorf("section %s is already in item %s", sourceSectionName, sourceItem.Title)
+		}
+
+		err := RenameSection(ctx, client, sourceItem, sourceSectionName, destinationSectionName)
+		if err != nil {
+			return &MoveError{Step: "renaming the section, nothing was moved", Err: err, Unchanged: true}
+		}
+		return nil
+	}
+
+	// keep what the destination looked like, to work out what the copy changed
+	original := cloneItem(*destinationItem)
+
+	err := CopySection(ctx, client, sourceItem, sourceSectionName, destinationItem, destinationSectionName)
+	if err != nil {
+		return &MoveError{Step: "copying the section, nothing was moved", Err: err, Unchanged: true}
+	}
+
+	undo := undoCopy(&original, destinationItem, destinationSectionName)
+
+	copiedItem, err := client.Items().Get(ctx, destinationItem.VaultID, destinationItem.ID)
+	if err != nil {
+		// CopySection left the destination at the version it wrote, which is enough to undo the copy
+		return rollbackCopy(ctx, client, destinationItem, undo, "reading back the copy", err)
+	}
+
+	err = VerifySection(sourceItem, sourceSectionName, &copiedItem, destinationSectionName)
+	if err != nil {
+		return rollbackCopy(ctx, client, &copiedItem, undo, "verifying the copy", err)
+	}
+
+	// we need to read back the source section... incase it's changed...
+	refreshedSourceItem, err := client.Items().Get(ctx, sourceItem.VaultID, sourceItem.ID)
+	if err != nil {
+		return rollbackCopy(ctx, client, &copiedItem, undo, "reading the source item", err)
+	}
+
+	err = RemoveSection(ctx, client, &refreshedSourceItem, sourceSectionName)
+	if err != nil {
+		// the write can fail after it was applied, eg on a timeout, and rolling back the copy then would lose the section
+		currentSourceItem, getErr := client.Items().Get(ctx, sourceItem.VaultID, sourceItem.ID)
+		if getErr != nil {
+			return &MoveError{
+				Step: "removing the source section, it is not known whether it was removed so the copy was kept",
+				Err:  err,
+			}
+		}
+
+		if FindSection(&currentSourceItem, sourceSectionName) != nil {
+			return rollbackCopy(ctx, client, &copiedItem, undo, "removing the source section", err)
+		}
+	}
+
+	return nil
+}
+
+// copyUndo is what CopySection changed in the destination
+type copyUndo struct {
+	// sectionID is set when the copy created the section
+	sectionID string
+	// added are the ids of the copied fields
+	added []string
+	// replaced are the fields of the destination section that copied fields replaced
+	replaced []onepassword.ItemField
+}
+
+// undoCopy works out what the copy changed by comparing the destination before and after it
+func undoCopy(original *onepassword.Item, copied *onepassword.Item, sectionName string) copyUndo {
+	var undo copyUndo
+
+	section := FindSection(copied, sectionName)
+	if section == nil {
+		return undo
+	}
+
+	if FindSection(original, sectionName) == nil {
+		undo.sectionID = section.ID
+	}
+
+	originalIDs := make(map[string]bool, len(original.Fields))
+	for _, field := range original.Fields {
+		originalIDs[field.ID] = true
+	}
+
+	copiedIDs := make(map[string]bool, len(copied.Fields))
+	for _, field := range copied.Fields {
+		copiedIDs[field.ID] = true
+		if field.SectionID != nil && *field.SectionID == section.ID && !originalIDs[field.ID] {
+			undo.added = append(undo.added, field.ID)
+		}
+	}
+
+	for _, field := range original.Fields {
+		if field.SectionID != nil && *field.SectionID == section.ID && !copiedIDs[field.ID] {
+			undo.replaced = append(undo.replaced, field)
+		}
+	}
+
+	return undo
+}
+
+// rollbackCopy undoes the copy, removing the copied fields and putting back the ones they replaced. Anything else
+// changed in the destination since the copy is left alone, and the section is only removed if the copy created it
+// and nothing else was added to it.
+func rollbackCopy(
+	ctx context.Context,
+	client *onepassword.Client,
+	destinationItem *onepassword.Item,
+	undo copyUndo,
+	step string,
+	cause error,
+) error {
+	_, err := writeItem(ctx, client, destinationItem, func(item *onepassword.Item) error {
+		item.Fields = slices.DeleteFunc(item.Fields, func(field onepassword.ItemField) bool {
+			return slices.Contains(undo.added, field.ID)
+		})
+
+		for _, field := range undo.replaced {
+			if !slices.ContainsFunc(item.Fields, func(v onepassword.ItemField) bool { return v.ID == field.ID }) {
+				item.Fields = append(item.Fields, field)
+			}
+		}
+
+		if undo.sectionID != "" && !slices.ContainsFunc(item.Fields, func(v onepassword.ItemField) bool {
+			return v.SectionID != nil && *v.SectionID == undo.sectionID
+		}) {
+			item.Sections = slices.DeleteFunc(item.Sections, func(v onepassword.ItemSection) bool {
+				return v.ID == undo.sectionID
+			})
+		}
+
+		slices.SortFunc(item.Fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
+			return strings.Compare(a.Title, b.Title)
+		})
+		return nil
+	})
+
+	if err != nil {
+		return &MoveError{
+			Step: step + ", the copy could not be rolled back (" + err.Error() + ") so the section is in both items",
+			Err:  cause,
+		}
+	}
+
+	return &MoveError{Step: step + ", the copy was rolled back and nothing was moved", Err: cause, Unchanged: true}
+}
+
+// RenameSection moves the fields of a section to another section of the same item, creating it if needed
+func RenameSection(
+	ctx context.Context,
+	client *onepassword.Client,
+	item *onepassword.Item,
+	sectionName string,
+	newSectionName string,
+) error {
+	_, err := writeItem(ctx, client, item, func(item *onepassword.Item) error {
+		section := FindSection(item, sectionName)
+		if section == nil {
+			return fmt.Errorf("sourceSection %s not found in item %s", sectionName, item.Title)
+		}
+
+		newSection := FindSection(item, newSectionName)
+		if newSection == nil {
+			// nothing there yet, so it's just a new title
+			for i, v := range item.Sections {
+				if v.ID == section.ID {
+					item.Sections[i].Title = newSectionName
+				}
+			}
+			return nil
+		}
+
+		// merge into the existing section, the moved fields replace fields with the same title
+		moving := make(map[string]bool)
+		for _, field := range item.Fields {
+			if field.SectionID != nil && *field.SectionID == section.ID {
+				moving[strings.TrimSpace(field.Title)] = true
+			}
+		}
+
+		fields := make([]onepassword.ItemField, 0, len(item.Fields))
+		for _, field := range item.Fields {
+			switch {
+			case field.SectionID != nil && *field.SectionID == section.ID:
+				sectionID := newSection.ID
+				field.SectionID = &sectionID
+			case field.SectionID != nil && *field.SectionID == newSection.ID && moving[strings.TrimSpace(field.Title)]:
+				continue
+			}
+			fields = append(fields, field)
+		}
+
+		item.Fields = fields
+		item.Sections = slices.DeleteFunc(item.Sections, func(v onepassword.ItemSection) bool {
+			return v.ID == section.ID
+		})
+		return nil
+	})
+
+	return err
+}
+
+// VerifySection checks that every field of the source section is in the destination section with the same value
+func VerifySection(
+	sourceItem *onepassword.Item,
+	sourceSectionName string,
+	destinationItem *onepassword.Item,
+	destinationSectionName string,
+) error {
+	sourceSection := FindSection(sourceItem, sourceSectionName)
+	destinationSection := FindSection(destinationItem, destinationSectionName)
+	if sourceSection == nil || destinationSection == nil {
+		return fmt.Errorf("section %s not found in item %s", destinationSectionName, destinationItem.Title)
+	}
+
+	copied := make(map[string]string)
+	for _, field := range destinationItem.Fields {
+		if field.SectionID != nil && *field.SectionID == destinationSection.ID {
+			copied[strings.TrimSpace(field.Title)] = field.Value
+		}
+	}
+
+	var missing []string
+	for _, field := range sourceItem.Fields {
+		if field.SectionID == nil || *field.SectionID != sourceSection.ID {
+			continue
+		}
+
+		value, ok := copied[strings.TrimSpace(field.Title)]
+		if !ok || value != field.Value {
+			missing = append(missing, field.Title)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("fields %s did not land in %s", strings.Join(missing, ", "), destinationItem.Title)
+	}
+
+	return nil
+}
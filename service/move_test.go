@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// failingPuts fails every Put to one item
+type failingPuts struct {
+	onepassword.ItemsAPI
+	itemID string
+}
+
+func (f *failingPuts) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	if item.ID == f.itemID {
+		return onepassword.Item{}, errors.New("put failed")
+	}
+	return f.ItemsAPI.Put(ctx, item)
+}
+
+// failingReadBack fails the Get that follows the first write to an item
+type failingReadBack struct {
+	onepassword.ItemsAPI
+	itemID string
+	puts   int
+	failed bool
+}
+
+func (f *failingReadBack) Get(ctx context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	if itemID == f.itemID && f.puts == 1 && !f.failed {
+		f.failed = true
+		return onepassword.Item{}, errors.New("get failed")
+	}
+	return f.ItemsAPI.Get(ctx, vaultID, itemID)
+}
+
+func (f *failingReadBack) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	if item.ID == f.itemID {
+		f.puts++
+	}
+	return f.ItemsAPI.Put(ctx, item)
+}
+
+// lostReplies applies every Put to one item, and then fails it as if the reply never arrived
+type lostReplies struct {
+	onepassword.ItemsAPI
+	itemID string
+}
+
+func (l *lostReplies) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	updatedItem, err := l.ItemsAPI.Put(ctx, item)
+	if err == nil && item.ID == l.itemID {
+		return onepassword.Item{}, context.DeadlineExceeded
+	}
+	return updatedItem, err
+}
+
+func newItemWithSection(t *testing.T, ctx context.Context, client *onepassword.Client, itemName string, sectionName string, environment map[string]any) *onepassword.Item {
+	vault, err := FindVaultWithName(ctx, client, "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := CreateItem(ctx, client, vault, itemName, sectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, err = UpdateItem(ctx, client, item, sectionName, &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func sectionValues(item *onepassword.Item, sectionName string) map[string]string {
+	section := FindSection(item, sectionName)
+	if section == nil {
+		return nil
+	}
+
+	values := make(map[string]string)
+	for _, field := range item.Fields {
+		if field.SectionID != nil && *field.SectionID == section.ID {
+			values[field.Title] = field.Value
+		}
+	}
+	return values
+}
+
+func TestMoveSection(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	source := newItemWithSection(t, ctx, client, "source", "staging", map[string]any{"A": "1", "B": "2"})
+	destination := newItemWithSection(t, ctx, client, "destination", "production", map[string]any{"A": "0", "C": "3"})
+
+	err := MoveSection(ctx, client, source, "staging", destination, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: source.VaultID}, "source")
+	destination, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: destination.VaultID}, "destination")
+
+	if FindSection(source, "staging") != nil {
+		t.Errorf("Expected the source section to be removed")
+	}
+
+	values := sectionValues(destination, "production")
+	if len(values) != 3 || values["A"] != "1" || values["B"] != "2" || values["C"] != "3" {
+		t.Errorf("Expected A=1 B=2 C=3 in the destination, got %v", values)
+	}
+}
+
+func TestMoveSectionRollback(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	source := newItemWithSection(t, ctx, client, "source", "staging", map[string]any{"A": "1"})
+	destination := newItemWithSection(t, ctx, client, "destination", "production", map[string]any{"C": "3"})
+
+	client.ItemsAPI = &failingPuts{ItemsAPI: client.ItemsAPI, itemID: source.ID}
+
+	err := MoveSection(ctx, client, source, "staging", destination, "production")
+
+	var moveError *MoveError
+	if !errors.As(err, &moveError) {
+		t.Fatalf("Expected a move error, got %v", err)
+	}
+
+	destination, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: destination.VaultID}, "destination")
+	values := sectionValues(destination, "production")
+	if len(values) != 1 || values["C"] != "3" {
+		t.Errorf("Expected the copy to be rolled back, got %v", values)
+	}
+}
+
+func TestMoveSectionSameItem(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	item := newItemWithSection(t, ctx, client, "item", "staging", map[string]any{"A": "1"})
+	same := cloneItem(*item)
+
+	err := MoveSection(ctx, client, item, "staging", &same, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: item.VaultID}, "item")
+	if FindSection(item, "staging") != nil || sectionValues(item, "production")["A"] != "1" {
+		t.Errorf("Expected staging to be renamed to production, got %v", item.Sections)
+	}
+
+	err = MoveSection(ctx, client, item, "production", item, "production")
+	if err == nil {
+		t.Errorf("Expected moving a section onto itself to fail")
+	}
+}
+
+func TestMoveSectionReadBackFails(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	source := newItemWithSection(t, ctx, client, "source", "staging", map[string]any{"A": "1"})
+	destination := newItemWithSection(t, ctx, client, "destination", "production", map[string]any{"C": "3"})
+
+	client.ItemsAPI = &failingReadBack{ItemsAPI: client.ItemsAPI, itemID: destination.ID}
+
+	err := MoveSection(ctx, client, source, "staging", destination, "production")
+
+	var moveError *MoveError
+	if !errors.As(err, &moveError) || !strings.Contains(moveError.Step, "the copy was rolled back") {
+		t.Fatalf("Expected the copy to be rolled back, got %v", err)
+	}
+
+	destination, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: destination.VaultID}, "destination")
+	values := sectionValues(destination, "production")
+	if len(values) != 1 || values["C"] != "3" {
+		t.Errorf("Expected the copy to be rolled back, got %v", values)
+	}
+}
+
+func TestMoveSectionRemoveAppliedDespiteError(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	source := newItemWithSection(t, ctx, client, "source", "staging", map[string]any{"A": "1"})
+	destination := newItemWithSection(t, ctx, client, "destination", "production", map[string]any{"C": "3"})
+
+	client.ItemsAPI = &lostReplies{ItemsAPI: client.ItemsAPI, itemID: source.ID}
+
+	err := MoveSection(ctx, client, source, "staging", destination, "production")
+	if err != nil {
+		t.Fatalf("Expected the move to succeed once the source section was found removed, got %v", err)
+	}
+
+	source, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: source.VaultID}, "source")
+	if FindSection(source, "staging") != nil {
+		t.Errorf("Expected the source section to be removed")
+	}
+
+	destination, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: destination.VaultID}, "destination")
+	values := sectionValues(destination, "production")
+	if len(values) != 2 || values["A"] != "1" || values["C"] != "3" {
+		t.Errorf("Expected the copy to be kept, got %v", values)
+	}
+}
+
+// editingPuts fails every Put to one item, after someone else edits another item
+type editingPuts struct {
+	onepassword.ItemsAPI
+	itemID string
+	edit   func()
+}
+
+func (e *editingPuts) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	if item.ID == e.itemID {
+		if e.edit != nil {
+			e.edit()
+			e.edit = nil
+		}
+		return onepassword.Item{}, errors.New("put failed")
+	}
+	return e.ItemsAPI.Put(ctx, item)
+}
+
+func TestMoveSectionRollbackKeepsOtherEdits(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	LockTimeout = time.Second
+	t.Cleanup(func() { LockTimeout = 0 })
+
+	source := newItemWithSection(t, ctx, client, "source", "staging", map[string]any{"A": "1", "B": "2"})
+	destination := newItemWithSection(t, ctx, client, "destination", "production", map[string]any{"A": "0", "C": "3"})
+
+	items := client.ItemsAPI
+	client.ItemsAPI = &editingPuts{ItemsAPI: items, itemID: source.ID, edit: func() {
+		// someone else adds a section to the destination between the copy and the rollback
+		edited, err := items.Get(ctx, destination.VaultID, destination.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		environment := map[string]any{"D": "4"}
+		unwrapped := &onepassword.Client{ItemsAPI: items, VaultsAPI: client.VaultsAPI}
+		if _, err := UpdateItem(ctx, unwrapped, &edited, "other", &environment); err != nil {
+			t.Fatal(err)
+		}
+	}}
+
+	err := MoveSection(ctx, client, source, "staging", destination, "production")
+
+	var moveError *MoveError
+	if !errors.As(err, &moveError) || !moveError.Unchanged {
+		t.Fatalf("Expected the copy to be rolled back, got %v", err)
+	}
+
+	destination, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: destination.VaultID}, "destination")
+
+	values := sectionValues(destination, "production")
+	if len(values) != 2 || values["A"] != "0" || values["C"] != "3" {
+		t.Errorf("Expected the replaced field to be put back, got %v", values)
+	}
+
+	if values := sectionValues(destination, "other"); values["D"] != "4" {
+		t.Errorf("Expected the other edit to survive the rollback, got %v", values)
+	}
+}
+
+func TestMoveSectionRollbackCreatedSection(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	source := newItemWithSection(t, ctx, client, "source", "staging", map[string]any{"A": "1"})
+	destination := newItemWithSection(t, ctx, client, "destination", "production", map[string]any{"C": "3"})
+
+	client.ItemsAPI = &failingPuts{ItemsAPI: client.ItemsAPI, itemID: source.ID}
+
+	err := MoveSection(ctx, client, source, "staging", destination, "new")
+	if err == nil {
+		t.Fatal("Expected the move to fail")
+	}
+
+	destination, _ = FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: destination.VaultID}, "destination")
+	if FindSection(destination, "new") != nil {
+		t.Errorf("Expected the section created by the copy to be removed")
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// ErrOffline is returned for changes, which can't be made from the offline cache
+var ErrOffline = errors.New("envop is offline, only reads can be served from the offline cache")
+
+// unreachableMessages are errors that mean 1password could not be reached, on top of the transient ones
+var unreachableMessages = []string{
+	"no such host",
+	"network is unreachable",
+	"dial tcp",
+	"connection closed",
+}
+
+// IsUnreachable reports whether the error means 1password could not be reached, rather than refusing the request
+func IsUnreachable(err error) bool {
+	if isTransient(err) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, unreachable := range unreachableMessages {
+		if strings.Contains(message, unreachable) {
+			return true
+		}
+	}
+	return false
+}
+
+// OfflineCache keeps the vaults and items that were last read, encrypted with a key derived from the token,
+// so they can be read when 1password can't be reached
+type OfflineCache struct {
+	Dir string
+	// Warn is called with the time the entry was saved, the first time an entry is served from the cache
+	Warn func(savedAt time.Time)
+	// SaveFailed is called the first time an entry can't be saved, the default prints a warning to stderr.
+	// What was read is still returned, the cache is only a fallback.
+	SaveFailed func(err error)
+
+	key      []byte
+	once     sync.Once
+	saveOnce sync.Once
+}
+
+// offlineEntry is what is stored, encrypted, in each cache file
+type offlineEntry struct {
+	SavedAt time.Time       `json:"saved_at"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// DefaultOfflineCacheDir returns the directory the offline cache is kept in unless offline_cache_dir is configured
+func DefaultOfflineCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envop", "offline"), nil
+}
+
+// NewOfflineCache creates a cache in the directory, only readable with the same token
+func NewOfflineCache(dir string, token string) (*OfflineCache, error) {
+	key, err := hkdf.Key(sha256.New, []byte(token), nil, "envop offline cache", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OfflineCache{Dir: dir, key: key}, nil
+}
+
+// path hashes the name so the file names don't give away vault and item ids
+func (c *OfflineCache) path(name string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(name))
+	return filepath.Join(c.Dir, hex.EncodeToString(mac.Sum(nil))[:32])
+}
+
+func (c *OfflineCache) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// save encrypts the value and writes it to the cache, the name is used as additional data so entries can't be swapped
+func (c *OfflineCache) save(name string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(offlineEntry{SavedAt: time.Now(), Value: raw})
+	if err != nil {
+		return err
+	}
+
+	aead, err := c.aead()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(c.Dir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(name)
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, aead.Seal(nonce, nonce, plaintext, []byte(name)), 0o600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// load decrypts the entry into value, and warns about its age the first time the cache is used
+func (c *OfflineCache) load(name string, value any) error {
+	ciphertext, err := os.ReadFile(c.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s is not in the offline cache", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	aead, err := c.aead()
+	if err != nil {
+		return err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return fmt.Errorf("offline cache entry for %s is corrupt", name)
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return fmt.Errorf("could not decrypt the offline cache entry for %s, was it saved with another token? %w", name, err)
+	}
+
+	var entry offlineEntry
+	err = json.Unmarshal(plaintext, &entry)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(entry.Value, value)
+	if err != nil {
+		return err
+	}
+
+	c.once.Do(func() {
+		if c.Warn != nil {
+			c.Warn(entry.SavedAt)
+		}
+	})
+	return nil
+}
+
+// WithOfflineCache saves every vault list, item list and item that is read, and serves them from the cache
+// when 1password can't be reached
+func WithOfflineCache(client *onepassword.Client, cache *OfflineCache) *onepassword.Client {
+	client.ItemsAPI = &offlineItems{ItemsAPI: client.ItemsAPI, cache: cache}
+	client.VaultsAPI = &offlineVaults{VaultsAPI: client.VaultsAPI, cache: cache}
+	return client
+}
+
+// OfflineClient returns a client that only reads from the cache
+func OfflineClient(cache *OfflineCache) *onepassword.Client {
+	return &onepassword.Client{
+		ItemsAPI:  &offlineItems{cache: cache},
+		VaultsAPI: &offlineVaults{cache: cache},
+	}
+}
+
+// saveFailed reports the first entry that could not be saved
+func (c *OfflineCache) saveFailed(err error) {
+	c.saveOnce.Do(func() {
+		if c.SaveFailed != nil {
+			c.SaveFailed(err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	})
+}
+
+// cachedRead makes the read, saving the result, or loads it from the cache when offline or unreachable
+func cachedRead[T any](cache *OfflineCache, name string, online bool, read func() (T, error)) (T, error) {
+	if online {
+		value, err := read()
+		if err == nil {
+			if err := cache.save(name, value); err != nil {
+				cache.saveFailed(fmt.Errorf("could not save %s to the offline cache: %w", name, err))
+			}
+			return value, nil
+		}
+		if !IsUnreachable(err) {
+			return value, err
+		}
+	}
+
+	var value T
+	err := cache.load(name, &value)
+	return value, err
+}
+
+type offlineVaults struct {
+	onepassword.VaultsAPI
+	cache *OfflineCache
+}
+
+func (v *offlineVaults) List(ctx context.Context) ([]onepassword.VaultOverview, error) {
+	return cachedRead(v.cache, "vaults", v.VaultsAPI != nil, func() ([]onepassword.VaultOverview, error) {
+		return v.VaultsAPI.List(ctx)
+	})
+}
+
+type offlineItems struct {
+	onepassword.ItemsAPI
+	cache *OfflineCache
+}
+
+func (i *offlineItems) List(ctx context.Context, vaultID string, filters ...onepassword.ItemListFilter) ([]onepassword.ItemOverview, error) {
+	if len(filters) > 0 && i.ItemsAPI != nil {
+		return i.ItemsAPI.List(ctx, vaultID, filters...)
+	}
+
+	return cachedRead(i.cache, "items/"+vaultID, i.ItemsAPI != nil, func() ([]onepassword.ItemOverview, error) {
+		return i.ItemsAPI.List(ctx, vaultID)
+	})
+}
+
+func (i *offlineItems) Get(ctx context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	return cachedRead(i.cache, "item/"+vaultID+"/"+itemID, i.ItemsAPI != nil, func() (onepassword.Item, error) {
+		return i.ItemsAPI.Get(ctx, vaultID, itemID)
+	})
+}
+
+func (i *offlineItems) Create(ctx context.Context, params onepassword.ItemCreateParams) (onepassword.Item, error) {
+	if i.ItemsAPI == nil {
+		return onepassword.Item{}, ErrOffline
+	}
+	return i.ItemsAPI.Create(ctx, params)
+}
+
+func (i *offlineItems) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	if i.ItemsAPI == nil {
+		return onepassword.Item{}, ErrOffline
+	}
+	return i.ItemsAPI.Put(ctx, item)
+}
+
+func (i *offlineItems) Delete(ctx context.Context, vaultID string, itemID string) error {
+	if i.ItemsAPI == nil {
+		return ErrOffline
+	}
+	return i.ItemsAPI.Delete(ctx, vaultID, itemID)
+}
+
+func (i *offlineItems) Archive(ctx context.Context, vaultID string, itemID string) error {
+	if i.ItemsAPI == nil {
+		return ErrOffline
+	}
+	return i.ItemsAPI.Archive(ctx, vaultID, itemID)
+}
+
+func (i *offlineItems) Shares() onepassword.ItemsSharesAPI {
+	if i.ItemsAPI == nil {
+		return nil
+	}
+	return i.ItemsAPI.Shares()
+}
+
+func (i *offlineItems) Files() onepassword.ItemsFilesAPI {
+	if i.ItemsAPI == nil {
+		return nil
+	}
+	return i.ItemsAPI.Files()
+}
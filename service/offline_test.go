@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// unreachableVaults fails every List as if the network was down
+type unreachableVaults struct {
+	onepassword.VaultsAPI
+}
+
+func (u *unreachableVaults) List(_ context.Context) ([]onepassword.VaultOverview, error) {
+	return nil, errors.New("dial tcp: lookup my.1password.com: no such host")
+}
+
+func TestOfflineCache(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+
+	client, _ := NewMemoryClient("vault")
+	newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+
+	cache, err := NewOfflineCache(dir, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = WithOfflineCache(client, cache)
+	_, err = ReadOnePassword(ctx, client, "vault", "item", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []time.Time
+	cache, _ = NewOfflineCache(dir, "token")
+	cache.Warn = func(savedAt time.Time) {
+		warnings = append(warnings, savedAt)
+	}
+
+	client.VaultsAPI = &unreachableVaults{}
+	client = WithOfflineCache(client, cache)
+
+	env, err := ReadOnePassword(ctx, client, "vault", "item", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env["A"] != int64(1) {
+		t.Errorf("Expected A to be served from the cache, got %v", env)
+	}
+
+	if len(warnings) != 1 {
+		t.Errorf("Expected one warning about the age of the cache, got %d", len(warnings))
+	}
+
+	offline := OfflineClient(cache)
+	_, err = CreateItem(ctx, offline, &onepassword.VaultOverview{ID: "vault"}, "item", "production")
+	if !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected changes to fail offline, got %v", err)
+	}
+
+	otherCache, _ := NewOfflineCache(dir, "other token")
+	_, err = ReadOnePassword(ctx, OfflineClient(otherCache), "vault", "item", "production")
+	if err == nil {
+		t.Errorf("Expected the cache to be unreadable with another token")
+	}
+}
+
+func TestOfflineCacheSaveFails(t *testing.T) {
+	ctx := t.Context()
+
+	// a directory can't be created inside a file, even by root
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, nil, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewOfflineCache(filepath.Join(file, "offline"), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var failures []error
+	cache.SaveFailed = func(err error) {
+		failures = append(failures, err)
+	}
+
+	client, _ := NewMemoryClient("vault")
+	newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+	client = WithOfflineCache(client, cache)
+
+	env, err := ReadOnePassword(ctx, client, "vault", "item", "production")
+	if err != nil {
+		t.Fatalf("Expected the read to succeed when the cache can't be saved, got %v", err)
+	}
+
+	if env["A"] != int64(1) {
+		t.Errorf("Expected A to be 1, got %v", env)
+	}
+
+	if len(failures) != 1 {
+		t.Errorf("Expected one warning about the cache, got %v", failures)
+	}
+}
@@ -52,9 +52,15 @@ func stringishToAny(stringish string) any {
 	return stringish
 }
 
-func NewClientFromToken(token string) (*onepassword.Client, error) {
+// NewClientFromToken creates a 1password client, if the token is empty it is resolved from the helpers
+func NewClientFromToken(ctx context.Context, token string, helpers ...TokenHelper) (*onepassword.Client, error) {
+	token, err := ResolveToken(ctx, token, helpers...)
+	if err != nil {
+		return nil, err
+	}
+
 	return onepassword.NewClient(
-		context.Background(),
+		ctx,
 		onepassword.WithServiceAccountToken(token),
 		onepassword.WithIntegrationInfo("envop", "v0.0.0"),
 	)
@@ -85,6 +91,7 @@ func EnvironmentToFields(
 
 // CreateItem creates a new 1password item in the specified vault, from the provided environment
 func CreateItem(
+	ctx context.Context,
 	client *onepassword.Client,
 	vault *onepassword.VaultOverview,
 	itemName string,
@@ -112,7 +119,7 @@ func CreateItem(
 		Category: onepassword.ItemCategoryServer,
 	}
 
-	item, err := client.Items().Create(context.Background(), itemParams)
+	item, err := client.Items().Create(ctx, itemParams)
 	if err != nil {
 		return nil, err
 	}
@@ -121,100 +128,95 @@ func CreateItem(
 
 // UpdateItem updates them
 func UpdateItem(
+	ctx context.Context,
 	client *onepassword.Client,
 	item *onepassword.Item,
 	sectionName string,
 	environment *map[string]any,
 ) (*onepassword.Item, error) {
-
-	// does the section exist?
-	var section = onepassword.ItemSection{}
-	for _, v := range item.Sections {
-		if v.Title == sectionName {
-			section = v
-			break
+	return writeItem(ctx, client, item, func(item *onepassword.Item) error {
+		// does the section exist?
+		var section = onepassword.ItemSection{}
+		for _, v := range item.Sections {
+			if v.Title == sectionName {
+				section = v
+				break
+			}
 		}
-	}
 
-	// if not, make it
-	if section.ID == "" {
-		section = onepassword.ItemSection{
-			ID:    uuid.New().String(),
-			Title: sectionName,
+		// if not, make it
+		if section.ID == "" {
+			section = onepassword.ItemSection{
+				ID:    uuid.New().String(),
+				Title: sectionName,
+			}
+			item.Sections = append(item.Sections, section)
 		}
-		item.Sections = append(item.Sections, section)
-	}
 
-	l := max(len(*environment), len(item.Fields))
+		l := max(len(*environment), len(item.Fields))
 
-	fieldMap := make(map[string]onepassword.ItemField, l)
-	fields := make([]onepassword.ItemField, 0, l)
+		fieldMap := make(map[string]onepassword.ItemField, l)
+		fields := make([]onepassword.ItemField, 0, l)
 
-	// filter out the items that are in our section, vs not
-	for _, field := range item.Fields {
-		if *field.SectionID == section.ID {
-			fieldMap[strings.TrimSpace(field.Title)] = field
-		} else {
-			fields = append(fields, field)
+		// filter out the items that are in our section, vs not
+		for _, field := range item.Fields {
+			if field.SectionID != nil && *field.SectionID == section.ID {
+				fieldMap[strings.TrimSpace(field.Title)] = field
+			} else {
+				fields = append(fields, field)
+			}
 		}
-	}
-
-	for _, v := range *EnvironmentToFields(environment, &section) {
-		fieldMap[v.Title] = v
-	}
 
-	for _, v := range fieldMap {
-		fields = append(fields, v)
-	}
+		for _, v := range *EnvironmentToFields(environment, &section) {
+			fieldMap[v.Title] = v
+		}
 
-	slices.SortFunc(fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
-		return strings.Compare(a.Title, b.Title)
-	})
+		for _, v := range fieldMap {
+			fields = append(fields, v)
+		}
 
-	item.Fields = fields
+		slices.SortFunc(fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
+			return strings.Compare(a.Title, b.Title)
+		})
 
-	updatedItem, err := client.Items().Put(context.Background(), *item)
-	if err != nil {
-		return nil, err
-	}
-	return &updatedItem, nil
+		item.Fields = fields
+		return nil
+	})
 }
 
-func ReindexItem(client *onepassword.Client, item *onepassword.Item) (*onepassword.Item, error) {
-	sectionMap := make(map[string]onepassword.ItemSection, len(item.Sections))
-	for _, section := range item.Sections {
-		oldId := section.ID
-		section.ID = uuid.New().String()
-		sectionMap[oldId] = section
-	}
+func ReindexItem(ctx context.Context, client *onepassword.Client, item *onepassword.Item) (*onepassword.Item, error) {
+	return writeItem(ctx, client, item, func(item *onepassword.Item) error {
+		sectionMap := make(map[string]onepassword.ItemSection, len(item.Sections))
+		for _, section := range item.Sections {
+			oldId := section.ID
+			section.ID = uuid.New().String()
+			sectionMap[oldId] = section
+		}
 
-	item.Sections = make([]onepassword.ItemSection, 0, len(sectionMap))
-	for _, section := range sectionMap {
-		item.Sections = append(item.Sections, section)
-	}
+		item.Sections = make([]onepassword.ItemSection, 0, len(sectionMap))
+		for _, section := range sectionMap {
+			item.Sections = append(item.Sections, section)
+		}
 
-	for i, field := range item.Fields {
-		field.ID = uuid.New().String()
-		newSectionId := sectionMap[*field.SectionID].ID
-		field.SectionID = &newSectionId
-		item.Fields[i] = field
-	}
+		for i, field := range item.Fields {
+			field.ID = uuid.New().String()
+			if field.SectionID != nil {
+				newSectionId := sectionMap[*field.SectionID].ID
+				field.SectionID = &newSectionId
+			}
+			item.Fields[i] = field
+		}
 
-	slices.SortFunc(item.Fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
-		return strings.Compare(a.Title, b.Title)
+		slices.SortFunc(item.Fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
+			return strings.Compare(a.Title, b.Title)
+		})
+		return nil
 	})
-
-	updatedItem, err := client.Items().Put(context.Background(), *item)
-	if err != nil {
-		return nil, err
-	}
-
-	return &updatedItem, nil
 }
 
 // FindVaultWithName retrieves a 1password vault by name
-func FindVaultWithName(client *onepassword.Client, vaultName string) (*onepassword.VaultOverview, error) {
-	vaults, err := client.Vaults().List(context.Background())
+func FindVaultWithName(ctx context.Context, client *onepassword.Client, vaultName string) (*onepassword.VaultOverview, error) {
+	vaults, err := client.Vaults().List(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -229,16 +231,16 @@ func FindVaultWithName(client *onepassword.Client, vaultName string) (*onepasswo
 }
 
 // FindItemWithName retrieves a 1password item from the specified vault by name
-func FindItemWithName(client *onepassword.Client, vault *onepassword.VaultOverview, itemName string) (*onepassword.Item, error) {
+func FindItemWithName(ctx context.Context, client *onepassword.Client, vault *onepassword.VaultOverview, itemName string) (*onepassword.Item, error) {
 
-	items, err := client.Items().List(context.Background(), vault.ID)
+	items, err := client.Items().List(ctx, vault.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	for i := range items {
 		if items[i].Title == itemName {
-			item, err := client.Items().Get(context.Background(), vault.ID, items[i].ID)
+			item, err := client.Items().Get(ctx, vault.ID, items[i].ID)
 			if err != nil {
 				return nil, err
 			}
@@ -258,6 +260,7 @@ func FindSection(item *onepassword.Item, sectionName string) *onepassword.ItemSe
 }
 
 func CopySection(
+	ctx context.Context,
 	client *onepassword.Client,
 	sourceItem *onepassword.Item,
 	sourceSectionName string,
@@ -271,105 +274,108 @@ func CopySection(
 		return fmt.Errorf("sourceSection %s not found in item %s", sourceSectionName, sourceItem.Title)
 	}
 
-	destinationSection := FindSection(destinationItem, destinationSectionName)
-	if destinationSection == nil {
-		destinationSection = &onepassword.ItemSection{
-			ID:    uuid.New().String(),
-			Title: destinationSectionName,
+	_, err := writeItem(ctx, client, destinationItem, func(destinationItem *onepassword.Item) error {
+		destinationSection := FindSection(destinationItem, destinationSectionName)
+		if destinationSection == nil {
+			destinationSection = &onepassword.ItemSection{
+				ID:    uuid.New().String(),
+				Title: destinationSectionName,
+			}
+			destinationItem.Sections = append(destinationItem.Sections, *destinationSection)
 		}
-		destinationItem.Sections = append(destinationItem.Sections, *destinationSection)
-	}
 
-	// find fields in sourceSection...
-	// and grab them...
-	for _, v := range sourceItem.Fields {
-		if *v.SectionID == sourceSection.ID {
-			v.ID = uuid.New().String()
-			*v.SectionID = destinationSection.ID
-			destinationItem.Fields = append(destinationItem.Fields, v)
+		// fields being copied replace the ones with the same title in the destination section
+		copying := make(map[string]bool)
+		for _, v := range sourceItem.Fields {
+			if v.SectionID != nil && *v.SectionID == sourceSection.ID {
+				copying[strings.TrimSpace(v.Title)] = true
+			}
 		}
-	}
-
-	slices.SortFunc(destinationItem.Fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
-		return strings.Compare(a.Title, b.Title)
-	})
 
-	updatedItem, err := client.Items().Put(context.Background(), *destinationItem)
-	if err != nil {
-		return err
-	}
+		destinationItem.Fields = slices.DeleteFunc(destinationItem.Fields, func(v onepassword.ItemField) bool {
+			return v.SectionID != nil && *v.SectionID == destinationSection.ID && copying[strings.TrimSpace(v.Title)]
+		})
+
+		// find fields in sourceSection...
+		// and grab them...
+		for _, v := range sourceItem.Fields {
+			if v.SectionID != nil && *v.SectionID == sourceSection.ID {
+				// the section id is shared with the source field, so it needs a copy of its own
+				sectionID := destinationSection.ID
+				v.ID = uuid.New().String()
+				v.SectionID = &sectionID
+				destinationItem.Fields = append(destinationItem.Fields, v)
+			}
+		}
 
-	destinationItem = &updatedItem
+		slices.SortFunc(destinationItem.Fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
+			return strings.Compare(a.Title, b.Title)
+		})
+		return nil
+	})
 
-	return nil
+	return err
 }
 
 func RemoveSection(
+	ctx context.Context,
 	client *onepassword.Client,
 	item *onepassword.Item,
 	sectionName string,
 ) error {
 
-	section := FindSection(item, sectionName)
-	if section == nil {
+	if FindSection(item, sectionName) == nil {
 		return fmt.Errorf("sourceSection %s not found in item %s", sectionName, item.Title)
 	}
 
-	fields := make([]onepassword.ItemField, 0, len(item.Fields))
-	for _, field := range item.Fields {
-		if *field.SectionID != section.ID {
-			fields = append(fields, field)
+	_, err := writeItem(ctx, client, item, func(item *onepassword.Item) error {
+		section := FindSection(item, sectionName)
+		if section == nil {
+			return nil
 		}
-	}
 
-	item.Fields = fields
-
-	updatedItem, err := client.Items().Put(context.Background(), *item)
-	if err != nil {
-		return err
-	}
+		fields := make([]onepassword.ItemField, 0, len(item.Fields))
+		for _, field := range item.Fields {
+			if field.SectionID == nil || *field.SectionID != section.ID {
+				fields = append(fields, field)
+			}
+		}
 
-	item = &updatedItem
+		item.Fields = fields
+		item.Sections = slices.DeleteFunc(item.Sections, func(v onepassword.ItemSection) bool {
+			return v.ID == section.ID
+		})
+		return nil
+	})
 
-	return nil
+	return err
 }
 
-func MoveSection(
+// ReadOnePassword reads a section of an item as an environment
+func ReadOnePassword(
+	ctx context.Context,
 	client *onepassword.Client,
-	sourceItem *onepassword.Item,
-	sourceSectionName string,
-	destinationItem *onepassword.Item,
-	destinationSectionName string,
-) error {
-
-	err := CopySection(client, sourceItem, sourceSectionName, destinationItem, destinationSectionName)
-	if err != nil {
-		return err
-	}
-
-	// we need to read back the source section... incase it's changed...
-	refreshedSourceItem, err := client.Items().Get(context.Background(), sourceItem.VaultID, sourceItem.ID)
-
-	err = RemoveSection(client, &refreshedSourceItem, sourceSectionName)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	vaultName string,
+	itemName string,
+	sectionName string,
+) (map[string]any, error) {
+	return ReadSections(ctx, client, vaultName, itemName, sectionName)
 }
 
-func ReadOnePassword(
+// ReadSections reads several sections of an item into one environment, see ItemEnvironment
+func ReadSections(
+	ctx context.Context,
 	client *onepassword.Client,
 	vaultName string,
 	itemName string,
-	sectionName string,
+	sectionNames ...string,
 ) (map[string]any, error) {
-	vault, err := FindVaultWithName(client, vaultName)
+	vault, err := FindVaultWithName(ctx, client, vaultName)
 	if err != nil {
 		return nil, err
 	}
 
-	item, err := FindItemWithName(client, vault, itemName)
+	item, err := FindItemWithName(ctx, client, vault, itemName)
 	if err != nil {
 		return nil, err
 	}
@@ -378,25 +384,39 @@ func ReadOnePassword(
 		return nil, fmt.Errorf("item %s not found in vault", itemName)
 	}
 
-	var section *onepassword.ItemSection
-	if sectionName != "" {
-
-	}
-
-	var environment map[string]any
-
-	if sectionName == "" {
+	return ItemEnvironment(item, sectionNames...)
+}
 
-	} else {
+// ItemEnvironment merges the sections of the item into one environment, keys in later sections replace
+// the same keys in earlier ones. An empty section name reads the fields that are not in a section.
+func ItemEnvironment(item *onepassword.Item, sectionNames ...string) (map[string]any, error) {
+	environment := make(map[string]any)
+
+	for _, sectionName := range sectionNames {
+		sectionID := ""
+		if sectionName != "" {
+			section := FindSection(item, sectionName)
+			if section == nil {
+				return nil, fmt.Errorf("section %s not found in item %s", sectionName, item.Title)
+			}
+			sectionID = section.ID
+		}
 
-		section = FindSection(item, sectionName)
 		environment = collection.Reduce(item.Fields, func(env map[string]any, v onepassword.ItemField) map[string]any {
-			if section.ID == *v.SectionID {
+			if (v.SectionID == nil && sectionID == "") || (v.SectionID != nil && *v.SectionID == sectionID) {
 				env[strings.TrimSpace(v.Title)] = stringishToAny(v.Value)
 			}
 			return env
-		}, make(map[string]any))
+		}, environment)
 	}
 
 	return environment, nil
 }
+
+// EnvironmentToStrings converts the values back to the strings they would be written as in an env file
+func EnvironmentToStrings(environment map[string]any) map[string]string {
+	return collection.ReduceMap(environment, func(v any, k string, env map[string]string) map[string]string {
+		env[k] = anyToStringish(v)
+		return env
+	}, make(map[string]string, len(environment)))
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Process runs a command with secrets added to its environment, and can stop it gracefully
+type Process struct {
+	Command []string
+	// StopSignal is sent to stop the process, it is killed if it is still running after the GracePeriod
+	StopSignal  os.Signal
+	GracePeriod time.Duration
+
+	cmd    *exec.Cmd
+	exited chan error
+}
+
+// Environ adds the environment to base, a list of key=value pairs like os.Environ, replacing keys that are already set
+func Environ(base []string, env map[string]any) []string {
+	values := EnvironmentToStrings(env)
+
+	environ := slices.DeleteFunc(slices.Clone(base), func(v string) bool {
+		key, _, _ := strings.Cut(v, "=")
+		_, ok := values[key]
+		return ok
+	})
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		environ = append(environ, key+"="+values[key])
+	}
+	return environ
+}
+
+// tokenVariables are the service account token, and the token config that viper reads from the environment
+var tokenVariables = []string{
+	"OP_SERVICE_ACCOUNT_TOKEN",
+	"TOKEN_COMMAND",
+	"TOKEN_FILE",
+	"TOKEN_KEYRING",
+	"TOKEN_KEYRING_ACCOUNT",
+	"TOKEN_EXPIRES_AT",
+}
+
+// WithoutTokens removes the tokenVariables from a list of key=value pairs like os.Environ. Commands that envop
+// starts never need them.
+func WithoutTokens(environ []string) []string {
+	return slices.DeleteFunc(slices.Clone(environ), func(v string) bool {
+		key, _, _ := strings.Cut(v, "=")
+		return slices.Contains(tokenVariables, strings.ToUpper(key))
+	})
+}
+
+// Start starts the command with the environment added to the current one, without the service account token
+func (p *Process) Start(env map[string]any) error {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = Environ(WithoutTokens(os.Environ()), env)
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	p.cmd = cmd
+	p.exited = exited
+	return nil
+}
+
+// Exited receives the result of Wait when the running process exits
+func (p *Process) Exited() <-chan error {
+	return p.exited
+}
+
+// Signal sends a signal to the running process
+func (p *Process) Signal(signal os.Signal) error {
+	return p.cmd.Process.Signal(signal)
+}
+
+// Stop sends the stop signal and waits for the process to exit, killing it after the grace period. It returns
+// the result of Wait, like Exited.
+func (p *Process) Stop() error {
+	if err := p.cmd.Process.Signal(p.StopSignal); err != nil {
+		p.cmd.Process.Kill()
+	}
+
+	select {
+	case err := <-p.exited:
+		return err
+	case <-time.After(p.GracePeriod):
+	}
+
+	p.cmd.Process.Kill()
+	return <-p.exited
+}
+
+// Restart stops the running process and starts it again with the new environment
+func (p *Process) Restart(env map[string]any) error {
+	_ = p.Stop()
+	return p.Start(env)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestEnviron(t *testing.T) {
+	environ := Environ([]string{"PATH=/bin", "A=old"}, map[string]any{"A": "new", "B": int64(2)})
+
+	expected := []string{"PATH=/bin", "A=new", "B=2"}
+	if !slices.Equal(environ, expected) {
+		t.Errorf("Expected %v, got %v", expected, environ)
+	}
+}
+
+func TestWithoutTokens(t *testing.T) {
+	environ := WithoutTokens([]string{
+		"PATH=/bin",
+		"OP_SERVICE_ACCOUNT_TOKEN=ops_abc",
+		"TOKEN_COMMAND=pass envop",
+		"token_file=/tmp/token",
+		"TOKEN_KEYRING_ACCOUNT=ci",
+		"TOKEN_FOO=mine",
+		"TOKEN_URL=https://example.com/token",
+	})
+
+	// only envop's own token variables are removed
+	expected := []string{"PATH=/bin", "TOKEN_FOO=mine", "TOKEN_URL=https://example.com/token"}
+	if !slices.Equal(environ, expected) {
+		t.Errorf("Expected %v, got %v", expected, environ)
+	}
+}
+
+func TestProcessEnvironment(t *testing.T) {
+	t.Setenv("OP_SERVICE_ACCOUNT_TOKEN", "ops_abc")
+	t.Setenv("TOKEN_FILE", "/tmp/token")
+
+	process := &Process{
+		Command: []string{"/bin/sh", "-c", `test -z "$OP_SERVICE_ACCOUNT_TOKEN" && test -z "$TOKEN_FILE" && test "$A" = 1`},
+	}
+
+	err := process.Start(map[string]any{"A": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-process.Exited(); err != nil {
+		t.Errorf("Expected the token to be left out of the environment of the process, got %v", err)
+	}
+}
+
+func TestProcessStop(t *testing.T) {
+	process := &Process{
+		Command:     []string{"/bin/sh", "-c", `trap "" TERM; exec sleep 10`},
+		StopSignal:  syscall.SIGTERM,
+		GracePeriod: 100 * time.Millisecond,
+	}
+
+	err := process.Start(map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// give the shell time to ignore the signal
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	err = process.Stop()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the process to be killed after the grace period, took %s", elapsed)
+	}
+
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		t.Errorf("Expected the exit status of the killed process, got %v", err)
+	}
+}
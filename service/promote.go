@@ -0,0 +1,127 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/1password/onepassword-sdk-go"
+	"github.com/google/uuid"
+)
+
+// sectionFields returns the fields in the section by title
+func sectionFields(item *onepassword.Item, sectionName string) map[string]onepassword.ItemField {
+	fields := make(map[string]onepassword.ItemField)
+
+	section := FindSection(item, sectionName)
+	if section == nil {
+		return fields
+	}
+
+	for _, field := range item.Fields {
+		if field.SectionID != nil && *field.SectionID == section.ID {
+			fields[strings.TrimSpace(field.Title)] = field
+		}
+	}
+	return fields
+}
+
+// DiffSections lists the keys that are added or changed going from the source section to the
+// destination section, and the keys that are only in the destination as removed. Values are not included.
+func DiffSections(
+	sourceItem *onepassword.Item,
+	sourceSectionName string,
+	destinationItem *onepassword.Item,
+	destinationSectionName string,
+) ([]FieldChange, error) {
+	if FindSection(sourceItem, sourceSectionName) == nil {
+		return nil, fmt.Errorf("sourceSection %s not found in item %s", sourceSectionName, sourceItem.Title)
+	}
+
+	sourceFields := sectionFields(sourceItem, sourceSectionName)
+	destinationFields := sectionFields(destinationItem, destinationSectionName)
+
+	changes := make([]FieldChange, 0)
+	for title, field := range sourceFields {
+		destinationField, ok := destinationFields[title]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{Section: destinationSectionName, Title: title, Kind: "added"})
+		case destinationField.Value != field.Value:
+			changes = append(changes, FieldChange{Section: destinationSectionName, Title: title, Kind: "changed"})
+		}
+	}
+
+	for title := range destinationFields {
+		if _, ok := sourceFields[title]; !ok {
+			changes = append(changes, FieldChange{Section: destinationSectionName, Title: title, Kind: "removed"})
+		}
+	}
+
+	slices.SortFunc(changes, func(a FieldChange, b FieldChange) int {
+		return cmp.Compare(a.Title, b.Title)
+	})
+
+	return changes, nil
+}
+
+// PromoteKeys copies the values of the keys from the source section into the destination section.
+// Keys that already exist in the destination are updated in place, and keys that are not promoted are left alone.
+func PromoteKeys(
+	ctx context.Context,
+	client *onepassword.Client,
+	sourceItem *onepassword.Item,
+	sourceSectionName string,
+	destinationItem *onepassword.Item,
+	destinationSectionName string,
+	keys []string,
+) (*onepassword.Item, error) {
+	sourceFields := sectionFields(sourceItem, sourceSectionName)
+	for _, key := range keys {
+		if _, ok := sourceFields[key]; !ok {
+			return nil, fmt.Errorf("key %s not found in section %s of item %s", key, sourceSectionName, sourceItem.Title)
+		}
+	}
+
+	return writeItem(ctx, client, destinationItem, func(item *onepassword.Item) error {
+		section := FindSection(item, destinationSectionName)
+		if section == nil {
+			section = &onepassword.ItemSection{
+				ID:    uuid.New().String(),
+				Title: destinationSectionName,
+			}
+			item.Sections = append(item.Sections, *section)
+		}
+
+		promoted := make(map[string]bool, len(keys))
+		for i, field := range item.Fields {
+			title := strings.TrimSpace(field.Title)
+			if field.SectionID == nil || *field.SectionID != section.ID || !slices.Contains(keys, title) {
+				continue
+			}
+
+			item.Fields[i].Value = sourceFields[title].Value
+			item.Fields[i].FieldType = sourceFields[title].FieldType
+			promoted[title] = true
+		}
+
+		for _, key := range keys {
+			if promoted[key] {
+				continue
+			}
+
+			field := sourceFields[key]
+			sectionID := section.ID
+			field.ID = uuid.New().String()
+			field.SectionID = &sectionID
+			item.Fields = append(item.Fields, field)
+		}
+
+		slices.SortFunc(item.Fields, func(a onepassword.ItemField, b onepassword.ItemField) int {
+			return strings.Compare(a.Title, b.Title)
+		})
+		return nil
+	})
+}
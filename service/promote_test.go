@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestPromoteKeys(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	item := newItemWithSection(t, ctx, client, "item", "staging", map[string]any{"A": "new", "B": "2", "HOST": "staging.local"})
+	environment := map[string]any{"A": "old", "C": "3", "HOST": "production.local"}
+	item, err := UpdateItem(ctx, client, item, "production", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := DiffSections(item, "staging", item, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[string]string)
+	for _, change := range changes {
+		kinds[change.Title] = change.Kind
+	}
+
+	if kinds["A"] != "changed" || kinds["B"] != "added" || kinds["C"] != "removed" || kinds["HOST"] != "changed" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+
+	item, err = PromoteKeys(ctx, client, item, "staging", item, "production", []string{"A", "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := sectionValues(item, "production")
+	if len(values) != 4 || values["A"] != "new" || values["B"] != "2" || values["C"] != "3" || values["HOST"] != "production.local" {
+		t.Errorf("Unexpected production values %v", values)
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/1password/onepassword-sdk-go"
+	"golang.org/x/sync/errgroup"
+)
+
+// Workers bounds how many items are fetched at the same time
+var Workers = 8
+
+// lookup is a list that is fetched once, callers asking while it is being fetched wait for it
+type lookup[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// lookups are the lists fetched so far, by key
+type lookups[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*lookup[T]
+}
+
+// get returns the list for the key, fetching it if needed. Failed fetches are not remembered.
+func (l *lookups[T]) get(key string, fetch func() (T, error)) (T, error) {
+	l.mu.Lock()
+	entry, ok := l.entries[key]
+	if ok {
+		l.mu.Unlock()
+		<-entry.done
+		return entry.value, entry.err
+	}
+
+	entry = &lookup[T]{done: make(chan struct{})}
+	if l.entries == nil {
+		l.entries = make(map[string]*lookup[T])
+	}
+	l.entries[key] = entry
+	l.mu.Unlock()
+
+	entry.value, entry.err = fetch()
+	close(entry.done)
+
+	if entry.err != nil {
+		l.mu.Lock()
+		if l.entries[key] == entry {
+			delete(l.entries, key)
+		}
+		l.mu.Unlock()
+	}
+	return entry.value, entry.err
+}
+
+// forget drops the list for the key, so it is fetched again
+func (l *lookups[T]) forget(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+}
+
+// WithLookupCache makes the client list vaults, and the items in a vault, once for the life of the process
+// instead of on every lookup by name. Items themselves are always fetched, so changes to their fields are
+// never missed. Creating, changing or deleting an item through the client lists its vault again.
+func WithLookupCache(client *onepassword.Client) *onepassword.Client {
+	client.VaultsAPI = &lookupVaults{VaultsAPI: client.VaultsAPI}
+	client.ItemsAPI = &lookupItems{ItemsAPI: client.ItemsAPI}
+	return client
+}
+
+type lookupVaults struct {
+	onepassword.VaultsAPI
+	vaults lookups[[]onepassword.VaultOverview]
+}
+
+func (v *lookupVaults) List(ctx context.Context) ([]onepassword.VaultOverview, error) {
+	return v.vaults.get("", func() ([]onepassword.VaultOverview, error) {
+		return v.VaultsAPI.List(ctx)
+	})
+}
+
+type lookupItems struct {
+	onepassword.ItemsAPI
+	items lookups[[]onepassword.ItemOverview]
+}
+
+func (i *lookupItems) List(ctx context.Context, vaultID string, filters ...onepassword.ItemListFilter) ([]onepassword.ItemOverview, error) {
+	if len(filters) > 0 {
+		return i.ItemsAPI.List(ctx, vaultID, filters...)
+	}
+
+	return i.items.get(vaultID, func() ([]onepassword.ItemOverview, error) {
+		return i.ItemsAPI.List(ctx, vaultID)
+	})
+}
+
+func (i *lookupItems) Get(ctx context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	item, err := i.ItemsAPI.Get(ctx, vaultID, itemID)
+	if err != nil {
+		// the item may have been deleted since the vault was listed
+		i.items.forget(vaultID)
+	}
+	return item, err
+}
+
+func (i *lookupItems) Create(ctx context.Context, params onepassword.ItemCreateParams) (onepassword.Item, error) {
+	defer i.items.forget(params.VaultID)
+	return i.ItemsAPI.Create(ctx, params)
+}
+
+func (i *lookupItems) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	defer i.items.forget(item.VaultID)
+	return i.ItemsAPI.Put(ctx, item)
+}
+
+func (i *lookupItems) Delete(ctx context.Context, vaultID string, itemID string) error {
+	defer i.items.forget(vaultID)
+	return i.ItemsAPI.Delete(ctx, vaultID, itemID)
+}
+
+func (i *lookupItems) Archive(ctx context.Context, vaultID string, itemID string) error {
+	defer i.items.forget(vaultID)
+	return i.ItemsAPI.Archive(ctx, vaultID, itemID)
+}
+
+// GetItems fetches the items, at most Workers at a time. The items are returned in the same order as the ids.
+func GetItems(ctx context.Context, client *onepassword.Client, vaultID string, itemIDs []string) ([]onepassword.Item, error) {
+	items := make([]onepassword.Item, len(itemIDs))
+
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(Workers)
+
+	for i, itemID := range itemIDs {
+		group.Go(func() error {
+			item, err := client.Items().Get(ctx, vaultID, itemID)
+			items[i] = item
+			return err
+		})
+	}
+
+	return items, group.Wait()
+}
+
+// FindItemsWithNames retrieves several items from the vault with one list and concurrent gets.
+// Items that don't exist are nil, like FindItemWithName.
+func FindItemsWithNames(ctx context.Context, client *onepassword.Client, vault *onepassword.VaultOverview, itemNames []string) ([]*onepassword.Item, error) {
+	overviews, err := client.Items().List(ctx, vault.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(overviews))
+	for _, overview := range overviews {
+		if _, ok := ids[overview.Title]; !ok {
+			ids[overview.Title] = overview.ID
+		}
+	}
+
+	var found []string
+	for _, itemName := range itemNames {
+		if id, ok := ids[itemName]; ok {
+			found = append(found, id)
+		}
+	}
+
+	fetched, err := GetItems(ctx, client, vault.ID, found)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*onepassword.Item, len(fetched))
+	for i := range fetched {
+		byID[fetched[i].ID] = &fetched[i]
+	}
+
+	items := make([]*onepassword.Item, len(itemNames))
+	for i, itemName := range itemNames {
+		if id, ok := ids[itemName]; ok {
+			items[i] = byID[id]
+		}
+	}
+	return items, nil
+}
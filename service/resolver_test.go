@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// slowItems adds a delay to every call, like a round trip to 1password
+type slowItems struct {
+	onepassword.ItemsAPI
+	delay time.Duration
+}
+
+func (s *slowItems) Get(ctx context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	time.Sleep(s.delay)
+	return s.ItemsAPI.Get(ctx, vaultID, itemID)
+}
+
+func (s *slowItems) List(ctx context.Context, vaultID string, filters ...onepassword.ItemListFilter) ([]onepassword.ItemOverview, error) {
+	time.Sleep(s.delay)
+	return s.ItemsAPI.List(ctx, vaultID, filters...)
+}
+
+func TestLookupCache(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	vaults := &countingVaults{VaultsAPI: client.VaultsAPI}
+	client.VaultsAPI = vaults
+	client = WithLookupCache(client)
+
+	newItemWithSection(t, ctx, client, "first", "production", map[string]any{"A": "1"})
+	newItemWithSection(t, ctx, client, "second", "production", map[string]any{"A": "2"})
+
+	for _, itemName := range []string{"first", "second", "first"} {
+		env, err := ReadOnePassword(ctx, client, "vault", itemName, "production")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(env) != 1 {
+			t.Errorf("Expected one key in %s, got %v", itemName, env)
+		}
+	}
+
+	if vaults.lists != 1 {
+		t.Errorf("Expected the vaults to be listed once, got %d lists", vaults.lists)
+	}
+}
+
+func TestFindItemsWithNames(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+
+	newItemWithSection(t, ctx, client, "first", "production", map[string]any{"A": "1"})
+	newItemWithSection(t, ctx, client, "second", "production", map[string]any{"A": "2"})
+
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+	items, err := FindItemsWithNames(ctx, client, vault, []string{"second", "missing", "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 3 || items[0].Title != "second" || items[1] != nil || items[2].Title != "first" {
+		t.Errorf("Expected second, nil and first, got %v", items)
+	}
+}
+
+// benchmarkVault fills a vault with items, with a delay on every call to the items api
+func benchmarkVault(b *testing.B, items int, delay time.Duration) (*onepassword.Client, *onepassword.VaultOverview) {
+	ctx := b.Context()
+	client, _ := NewMemoryClient("vault")
+	vault, _ := FindVaultWithName(ctx, client, "vault")
+
+	for i := range items {
+		_, err := CreateItem(ctx, client, vault, fmt.Sprintf("item-%04d", i), "production")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	client.ItemsAPI = &slowItems{ItemsAPI: client.ItemsAPI, delay: delay}
+	return client, vault
+}
+
+func benchmarkItemNames(count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("item-%04d", i*10)
+	}
+	return names
+}
+
+func BenchmarkFindItemWithName(b *testing.B) {
+	client, vault := benchmarkVault(b, 2000, 100*time.Microsecond)
+	names := benchmarkItemNames(20)
+
+	for b.Loop() {
+		for _, name := range names {
+			if _, err := FindItemWithName(b.Context(), client, vault, name); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkFindItemWithNameLookupCache(b *testing.B) {
+	client, vault := benchmarkVault(b, 2000, 100*time.Microsecond)
+	names := benchmarkItemNames(20)
+
+	for b.Loop() {
+		cached := WithLookupCache(&onepassword.Client{ItemsAPI: client.ItemsAPI, VaultsAPI: client.VaultsAPI})
+		for _, name := range names {
+			if _, err := FindItemWithName(b.Context(), cached, vault, name); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkFindItemsWithNames(b *testing.B) {
+	client, vault := benchmarkVault(b, 2000, 100*time.Microsecond)
+	names := benchmarkItemNames(20)
+
+	for b.Loop() {
+		if _, err := FindItemsWithNames(b.Context(), client, vault, names); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// RetryPolicy controls how failed SDK calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a call is made, including the first one
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles with each attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, unless the api asks for longer
+	MaxDelay time.Duration
+	// Budget is the total time a single call may spend waiting between attempts
+	Budget time.Duration
+	// Log is called for every retry, when set
+	Log func(format string, args ...any)
+}
+
+// DefaultRetryPolicy makes up to five attempts, so four retries, within a minute
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    15 * time.Second,
+		Budget:      time.Minute,
+	}
+}
+
+// retryAfterPattern picks the retry delay, in seconds, out of rate limit messages. The SDK doesn't expose the
+// Retry-After header, so this depends on it quoting the header in the message, without a match the backoff is used.
+var retryAfterPattern = regexp.MustCompile(`(?i)retry[- ]after:?\s*(\d+)`)
+
+// transientMessages are the error messages worth retrying that are not rate limits
+var transientMessages = []string{
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"temporarily unavailable",
+	"too many requests",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+}
+
+// notSentMessages are the connection errors that happen before a request is sent
+var notSentMessages = []string{
+	"connection refused",
+	"no such host",
+	"network is unreachable",
+}
+
+// isRateLimit reports whether the request was rejected by the rate limiter, so it had no effect
+func isRateLimit(err error) bool {
+	var rateLimit *onepassword.RateLimitExceededError
+	return errors.As(err, &rateLimit)
+}
+
+// isTransient reports whether the request could succeed if it is made again
+func isTransient(err error) bool {
+	if isRateLimit(err) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, transient := range transientMessages {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotSent reports whether the request was rate limited or never reached the api, so it had no effect.
+// Unlike isTransient it excludes timeouts and dropped connections, since the request may have been applied.
+func isNotSent(err error) bool {
+	if isRateLimit(err) {
+		return true
+	}
+
+	var opError *net.OpError
+	if errors.As(err, &opError) && opError.Op == "dial" {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, notSent := range notSentMessages {
+		if strings.Contains(message, notSent) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the delay the api asked for, if any
+func retryAfter(err error) time.Duration {
+	matches := retryAfterPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// do calls fn until it succeeds, fails with an error that retryable rejects, or the attempts or budget run out
+func (p RetryPolicy) do(ctx context.Context, operation string, retryable func(error) bool, fn func() error) error {
+	var waited time.Duration
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		// exponential backoff with full jitter, unless the api told us how long to wait
+		delay := min(p.BaseDelay<<(attempt-1), p.MaxDelay)
+		delay = time.Duration(rand.Int64N(int64(delay) + 1))
+		delay = max(delay, retryAfter(err))
+
+		if waited+delay > p.Budget {
+			return err
+		}
+		waited += delay
+
+		if p.Log != nil {
+			p.Log("%s failed (attempt %d of %d), retrying in %s: %v", operation, attempt, p.MaxAttempts, delay.Round(time.Millisecond), err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WithRetry makes the client retry rate limited and transient failures. Creates and deletes are
+// only retried when they were rate limited, and puts when they were not sent, since otherwise they may
+// have gone through.
+func WithRetry(client *onepassword.Client, policy RetryPolicy) *onepassword.Client {
+	client.ItemsAPI = &retryItems{ItemsAPI: client.ItemsAPI, policy: policy}
+	client.VaultsAPI = &retryVaults{VaultsAPI: client.VaultsAPI, policy: policy}
+	return client
+}
+
+type retryVaults struct {
+	onepassword.VaultsAPI
+	policy RetryPolicy
+}
+
+func (r *retryVaults) List(ctx context.Context) (vaults []onepassword.VaultOverview, err error) {
+	err = r.policy.do(ctx, "list vaults", isTransient, func() error {
+		vaults, err = r.VaultsAPI.List(ctx)
+		return err
+	})
+	return vaults, err
+}
+
+type retryItems struct {
+	onepassword.ItemsAPI
+	policy RetryPolicy
+}
+
+func (r *retryItems) Create(ctx context.Context, params onepassword.ItemCreateParams) (item onepassword.Item, err error) {
+	err = r.policy.do(ctx, "create item "+params.Title, isRateLimit, func() error {
+		item, err = r.ItemsAPI.Create(ctx, params)
+		return err
+	})
+	return item, err
+}
+
+func (r *retryItems) Get(ctx context.Context, vaultID string, itemID string) (item onepassword.Item, err error) {
+	err = r.policy.do(ctx, "get item "+itemID, isTransient, func() error {
+		item, err = r.ItemsAPI.Get(ctx, vaultID, itemID)
+		return err
+	})
+	return item, err
+}
+
+func (r *retryItems) Put(ctx context.Context, item onepassword.Item) (updatedItem onepassword.Item, err error) {
+	err = r.policy.do(ctx, "put item "+item.Title, isNotSent, func() error {
+		updatedItem, err = r.ItemsAPI.Put(ctx, item)
+		return err
+	})
+	return updatedItem, err
+}
+
+func (r *retryItems) Delete(ctx context.Context, vaultID string, itemID string) error {
+	return r.policy.do(ctx, "delete item "+itemID, isRateLimit, func() error {
+		return r.ItemsAPI.Delete(ctx, vaultID, itemID)
+	})
+}
+
+func (r *retryItems) List(ctx context.Context, vaultID string, filters ...onepassword.ItemListFilter) (items []onepassword.ItemOverview, err error) {
+	err = r.policy.do(ctx, "list items", isTransient, func() error {
+		items, err = r.ItemsAPI.List(ctx, vaultID, filters...)
+		return err
+	})
+	return items, err
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// flakyVaults fails the first few calls
+type flakyVaults struct {
+	onepassword.VaultsAPI
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyVaults) List(ctx context.Context) ([]onepassword.VaultOverview, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return f.VaultsAPI.List(ctx)
+}
+
+func TestRetryTransient(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	flaky := &flakyVaults{VaultsAPI: client.VaultsAPI, failures: 2, err: errors.New("connection reset by peer")}
+	client.VaultsAPI = flaky
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: time.Second}
+	client = WithRetry(client, policy)
+
+	_, err := FindVaultWithName(ctx, client, "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flaky.calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", flaky.calls)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	flaky := &flakyVaults{VaultsAPI: client.VaultsAPI, failures: 2, err: errors.New("invalid service account token")}
+	client.VaultsAPI = flaky
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: time.Second}
+	client = WithRetry(client, policy)
+
+	_, err := FindVaultWithName(ctx, client, "vault")
+	if err == nil || flaky.calls != 1 {
+		t.Errorf("Expected a single failed call, got %d calls and %v", flaky.calls, err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if delay := retryAfter(errors.New("rate limit exceeded, Retry-After: 3")); delay != 3*time.Second {
+		t.Errorf("Expected 3s, got %s", delay)
+	}
+
+	if delay := retryAfter(errors.New("rate limit exceeded")); delay != 0 {
+		t.Errorf("Expected no delay, got %s", delay)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	errs := map[error]bool{
+		io.EOF: true,
+		fmt.Errorf("reading: %w", io.ErrUnexpectedEOF): true,
+		errors.New("connection reset by peer"):         true,
+		errors.New("field geoffrey not found"):         false,
+		errors.New("invalid item reference"):           false,
+	}
+
+	for err, expected := range errs {
+		if transient := isTransient(err); transient != expected {
+			t.Errorf("Expected isTransient(%q) to be %t", err, expected)
+		}
+	}
+}
+
+func TestIsNotSent(t *testing.T) {
+	errs := map[error]bool{
+		&onepassword.RateLimitExceededError{}:                             true,
+		&net.OpError{Op: "dial", Err: errors.New("i/o timeout")}:          true,
+		errors.New("dial tcp: lookup my.1password.com: no such host"):     true,
+		errors.New("connect: connection refused"):                         true,
+		errors.New("context deadline exceeded (Client.Timeout exceeded)"): false,
+		errors.New("read tcp: connection reset by peer"):                  false,
+		fmt.Errorf("reading: %w", io.ErrUnexpectedEOF):                    false,
+	}
+
+	for err, expected := range errs {
+		if notSent := isNotSent(err); notSent != expected {
+			t.Errorf("Expected isNotSent(%q) to be %t", err, expected)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Schema declares the keys a section should have
+type Schema struct {
+	Keys map[string]KeySchema `json:"keys"`
+}
+
+// KeySchema declares the type of a key, and when it is required
+type KeySchema struct {
+	// Type is one of string, int, float, bool, url, enum or regex
+	Type string `json:"type"`
+	// Required keys must be present in every environment
+	Required bool `json:"required"`
+	// RequiredIn lists the environments the key must be present in
+	RequiredIn []string `json:"required_in"`
+	// Values are the allowed values of an enum
+	Values []string `json:"values"`
+	// Pattern is the regular expression a regex value must match
+	Pattern string `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// ValidationError is a problem with a single key
+type ValidationError struct {
+	Key     string
+	Message string
+}
+
+// SchemaError lists every key that doesn't match the schema
+type SchemaError struct {
+	Errors []ValidationError
+}
+
+func (e *SchemaError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, v := range e.Errors {
+		messages = append(messages, v.Key+": "+v.Message)
+	}
+	return "schema validation failed:\n  " + strings.Join(messages, "\n  ")
+}
+
+// ReadSchema reads a schema file in JSON format
+func ReadSchema(path string) (*Schema, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	err = json.Unmarshal(raw, &schema)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema %s: %w", path, err)
+	}
+
+	for key, keySchema := range schema.Keys {
+		switch keySchema.Type {
+		case "", "string", "int", "float", "bool", "url":
+		case "enum":
+			if len(keySchema.Values) == 0 {
+				return nil, fmt.Errorf("schema for %s is an enum without values", key)
+			}
+		case "regex":
+			keySchema.pattern, err = regexp.Compile(keySchema.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("schema for %s has an invalid pattern: %w", key, err)
+			}
+			schema.Keys[key] = keySchema
+		default:
+			return nil, fmt.Errorf("schema for %s has unknown type %s", key, keySchema.Type)
+		}
+	}
+
+	return &schema, nil
+}
+
+// Validate checks the environment against the schema, keys that are not in the schema are allowed
+func (s *Schema) Validate(envName string, env map[string]any) error {
+	keys := make([]string, 0, len(s.Keys))
+	for key := range s.Keys {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var errors []ValidationError
+	for _, key := range keys {
+		keySchema := s.Keys[key]
+
+		value, ok := env[key]
+		if !ok {
+			if keySchema.Required || (envName != "" && slices.Contains(keySchema.RequiredIn, envName)) {
+				errors = append(errors, ValidationError{Key: key, Message: "is required"})
+			}
+			continue
+		}
+
+		if message := keySchema.check(anyToStringish(value)); message != "" {
+			errors = append(errors, ValidationError{Key: key, Message: message})
+		}
+	}
+
+	if len(errors) > 0 {
+		return &SchemaError{Errors: errors}
+	}
+	return nil
+}
+
+// check returns what is wrong with the value, if anything
+func (k KeySchema) check(value string) string {
+	value = strings.TrimSpace(value)
+
+	switch k.Type {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an int"
+		}
+	case "float":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number"
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be a bool"
+		}
+	case "url":
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return "must be an absolute url"
+		}
+	case "enum":
+		if !slices.Contains(k.Values, value) {
+			return "must be one of " + strings.Join(k.Values, ", ")
+		}
+	case "regex":
+		if k.pattern == nil {
+			k.pattern = regexp.MustCompile(k.Pattern)
+		}
+		if !k.pattern.MatchString(value) {
+			return "must match " + k.Pattern
+		}
+	}
+
+	return ""
+}
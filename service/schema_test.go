@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	err := os.WriteFile(path, []byte(`{
+		"keys": {
+			"PORT": {"type": "int", "required": true},
+			"DEBUG": {"type": "bool"},
+			"API_URL": {"type": "url", "required_in": ["production"]},
+			"LOG_LEVEL": {"type": "enum", "values": ["debug", "info"]},
+			"REGION": {"type": "regex", "pattern": "^[a-z]+-[0-9]$"}
+		}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := ReadSchema(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := map[string]any{"PORT": int64(8080), "DEBUG": "true", "LOG_LEVEL": "info", "REGION": "eu-1", "OTHER": "x"}
+	if err := schema.Validate("staging", valid); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	if err := schema.Validate("production", valid); err == nil {
+		t.Errorf("Expected API_URL to be required in production")
+	}
+
+	invalid := map[string]any{"DEBUG": "maybe", "API_URL": "/relative", "LOG_LEVEL": "trace", "REGION": "eu"}
+	err = schema.Validate("staging", invalid)
+
+	var schemaError *SchemaError
+	if !errors.As(err, &schemaError) {
+		t.Fatalf("Expected a schema error, got %v", err)
+	}
+
+	if len(schemaError.Errors) != 5 {
+		t.Errorf("Expected 5 errors, got %v", schemaError.Errors)
+	}
+}
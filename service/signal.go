@@ -0,0 +1,21 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ParseSignal parses a signal name like HUP or SIGHUP, or a signal number
+func ParseSignal(name string) (syscall.Signal, error) {
+	if number, err := strconv.Atoi(name); err == nil {
+		return syscall.Signal(number), nil
+	}
+
+	signal, ok := signals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %s", name)
+	}
+	return signal, nil
+}
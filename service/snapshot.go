@@ -0,0 +1,372 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// Snapshot is a copy of an item as it was before envop wrote to it
+type Snapshot struct {
+	ID        string           `json:"id"`
+	TakenAt   time.Time        `json:"takenAt"`
+	Operation string           `json:"operation"`
+	Item      onepassword.Item `json:"item"`
+}
+
+// SnapshotStore keeps age encrypted snapshots in a local directory. The identity used to encrypt
+// them is generated on first use and kept in IdentityFile, which must be outside of the directory
+// so that a copy of the snapshots can't be decrypted on its own.
+type SnapshotStore struct {
+	Dir          string
+	IdentityFile string
+}
+
+// DefaultSnapshotDir returns the snapshot directory in the user config directory
+func DefaultSnapshotDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envop", "snapshots"), nil
+}
+
+// DefaultSnapshotIdentity returns the identity file in the user config directory, next to the snapshot directory
+func DefaultSnapshotIdentity() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "envop", "snapshot-identity.txt"), nil
+}
+
+// identity loads the snapshot identity, creating it if it does not exist yet. Identities kept in the
+// snapshot directory by earlier versions are moved to the identity file.
+func (s *SnapshotStore) identity() (*age.X25519Identity, error) {
+	if s.IdentityFile == "" {
+		return nil, errors.New("no snapshot identity file")
+	}
+
+	dir, err := filepath.Abs(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := filepath.Abs(s.IdentityFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("the snapshot identity %s must not be kept in the snapshot directory %s", s.IdentityFile, s.Dir)
+	}
+
+	legacy := filepath.Join(dir, "identity.txt")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(legacy); err == nil {
+			if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(legacy, path); err != nil {
+				return nil, fmt.Errorf("could not move the snapshot identity out of %s: %w", s.Dir, err)
+			}
+		}
+	}
+
+	raw, err := ReadPrivateFile(path)
+	if err == nil {
+		return age.ParseX25519Identity(strings.TrimSpace(raw))
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, err
+	}
+
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	if _, err := fh.WriteString(identity.String() + "\n"); err != nil {
+		return nil, err
+	}
+
+	return identity, fh.Close()
+}
+
+// Save writes an encrypted snapshot of the item
+func (s *SnapshotStore) Save(operation string, item onepassword.Item) (*Snapshot, error) {
+	identity, err := s.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	takenAt := time.Now().UTC()
+	snapshot := Snapshot{
+		ID:        takenAt.Format("20060102T150405.000000000") + "-" + item.ID,
+		TakenAt:   takenAt,
+		Operation: operation,
+		Item:      item,
+	}
+
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	fh, err := os.OpenFile(filepath.Join(s.Dir, snapshot.ID+".age"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	w, err := age.Encrypt(fh, identity.Recipient())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Load decrypts the snapshot with the given id
+func (s *SnapshotStore) Load(id string) (*Snapshot, error) {
+	identity, err := s.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	fh, err := os.Open(filepath.Join(s.Dir, filepath.Base(id)+".age"))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s not found: %w", id, err)
+	}
+	defer fh.Close()
+
+	r, err := age.Decrypt(fh, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	err = json.Unmarshal(raw, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// List returns all the snapshots, newest first
+func (s *SnapshotStore) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".age")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		snapshot, err := s.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+
+	slices.SortFunc(snapshots, func(a Snapshot, b Snapshot) int {
+		return b.TakenAt.Compare(a.TakenAt)
+	})
+
+	return snapshots, nil
+}
+
+// snapshotItems snapshots the stored copy of an item before it is overwritten or deleted
+type snapshotItems struct {
+	onepassword.ItemsAPI
+	store *SnapshotStore
+}
+
+// WithSnapshots makes the client snapshot every item before it is changed
+func WithSnapshots(client *onepassword.Client, store *SnapshotStore) *onepassword.Client {
+	client.ItemsAPI = &snapshotItems{ItemsAPI: client.ItemsAPI, store: store}
+	return client
+}
+
+func (s *snapshotItems) snapshot(ctx context.Context, operation string, vaultID string, itemID string) error {
+	// the item handed to Put has already been changed, so snapshot what is stored, which putItem has just read
+	current, ok := storedItem(ctx, vaultID, itemID)
+	if !ok {
+		var err error
+		current, err = s.ItemsAPI.Get(ctx, vaultID, itemID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := s.store.Save(operation, current)
+	if err != nil {
+		return fmt.Errorf("could not snapshot item %s: %w", current.Title, err)
+	}
+	return nil
+}
+
+func (s *snapshotItems) Put(ctx context.Context, item onepassword.Item) (onepassword.Item, error) {
+	if err := s.snapshot(ctx, "put", item.VaultID, item.ID); err != nil {
+		return onepassword.Item{}, err
+	}
+	return s.ItemsAPI.Put(ctx, item)
+}
+
+func (s *snapshotItems) Delete(ctx context.Context, vaultID string, itemID string) error {
+	if err := s.snapshot(ctx, "delete", vaultID, itemID); err != nil {
+		return err
+	}
+	return s.ItemsAPI.Delete(ctx, vaultID, itemID)
+}
+
+// FieldChange describes how a field differs between two versions of an item, without the values
+type FieldChange struct {
+	Section string
+	Title   string
+	Kind    string
+}
+
+// fieldKey identifies a field by its section title and field title, since ids change on reindex
+func fieldKey(item *onepassword.Item, field onepassword.ItemField) (string, string) {
+	section := ""
+	if field.SectionID != nil {
+		for _, v := range item.Sections {
+			if v.ID == *field.SectionID {
+				section = v.Title
+				break
+			}
+		}
+	}
+	return section, strings.TrimSpace(field.Title)
+}
+
+// DiffItems lists the fields that are added, removed or changed going from one item to another
+func DiffItems(from *onepassword.Item, to *onepassword.Item) []FieldChange {
+	type key struct{ section, title string }
+
+	fromValues := make(map[key]string, len(from.Fields))
+	for _, field := range from.Fields {
+		section, title := fieldKey(from, field)
+		fromValues[key{section, title}] = field.Value
+	}
+
+	changes := make([]FieldChange, 0)
+	seen := make(map[key]bool, len(to.Fields))
+	for _, field := range to.Fields {
+		section, title := fieldKey(to, field)
+		k := key{section, title}
+		seen[k] = true
+
+		value, ok := fromValues[k]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{Section: section, Title: title, Kind: "added"})
+		case value != field.Value:
+			changes = append(changes, FieldChange{Section: section, Title: title, Kind: "changed"})
+		}
+	}
+
+	for k := range fromValues {
+		if !seen[k] {
+			changes = append(changes, FieldChange{Section: k.section, Title: k.title, Kind: "removed"})
+		}
+	}
+
+	slices.SortFunc(changes, func(a FieldChange, b FieldChange) int {
+		return cmp.Or(strings.Compare(a.Section, b.Section), strings.Compare(a.Title, b.Title))
+	})
+
+	return changes
+}
+
+// RestoreSnapshot puts the sections and fields from the snapshot back onto the item. If the item
+// was deleted, eg by import --replace, an item with the same title is used, or it is recreated.
+func RestoreSnapshot(ctx context.Context, client *onepassword.Client, snapshot *Snapshot) (*onepassword.Item, error) {
+	current, err := CurrentItemForSnapshot(ctx, client, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		item, err := client.Items().Create(ctx, onepassword.ItemCreateParams{
+			Title:    snapshot.Item.Title,
+			Category: snapshot.Item.Category,
+			VaultID:  snapshot.Item.VaultID,
+			Sections: snapshot.Item.Sections,
+			Fields:   snapshot.Item.Fields,
+			Notes:    &snapshot.Item.Notes,
+			Tags:     snapshot.Item.Tags,
+			Websites: snapshot.Item.Websites,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &item, nil
+	}
+
+	return writeItem(ctx, client, current, func(item *onepassword.Item) error {
+		item.Sections = snapshot.Item.Sections
+		item.Fields = snapshot.Item.Fields
+		return nil
+	})
+}
+
+// CurrentItemForSnapshot finds the item the snapshot was taken from, by id or else by title,
+// returning nil if it no longer exists
+func CurrentItemForSnapshot(ctx context.Context, client *onepassword.Client, snapshot *Snapshot) (*onepassword.Item, error) {
+	current, err := client.Items().Get(ctx, snapshot.Item.VaultID, snapshot.Item.ID)
+	if err == nil {
+		return &current, nil
+	}
+
+	vault := &onepassword.VaultOverview{ID: snapshot.Item.VaultID}
+	return FindItemWithName(ctx, client, vault, snapshot.Item.Title)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+func newSnapshotStore(t *testing.T) *SnapshotStore {
+	dir := t.TempDir()
+	return &SnapshotStore{Dir: filepath.Join(dir, "snapshots"), IdentityFile: filepath.Join(dir, "identity.txt")}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	store := newSnapshotStore(t)
+
+	item := newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+
+	first, err := store.Save("put", *item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := store.Save("delete", *item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := store.Load(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Operation != "put" || sectionValues(&loaded.Item, "production")["A"] != "1" {
+		t.Errorf("Expected the put snapshot with A=1, got %+v", loaded)
+	}
+
+	snapshots, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ID != second.ID || snapshots[1].ID != first.ID {
+		t.Errorf("Expected both snapshots newest first, got %v", snapshots)
+	}
+
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".age" {
+			t.Errorf("Expected only snapshots in the snapshot directory, found %s", entry.Name())
+		}
+	}
+
+	other := &SnapshotStore{Dir: store.Dir, IdentityFile: filepath.Join(t.TempDir(), "identity.txt")}
+	if _, err := other.Load(first.ID); err == nil {
+		t.Errorf("Expected a snapshot to be unreadable without its identity")
+	}
+}
+
+func TestSnapshotIdentity(t *testing.T) {
+	t.Run("inside the snapshot directory", func(t *testing.T) {
+		dir := t.TempDir()
+		store := &SnapshotStore{Dir: dir, IdentityFile: filepath.Join(dir, "identity.txt")}
+
+		if _, err := store.Save("put", onepassword.Item{ID: "item"}); err == nil {
+			t.Errorf("Expected an identity in the snapshot directory to be refused")
+		}
+	})
+
+	t.Run("moved out of the snapshot directory", func(t *testing.T) {
+		store := newSnapshotStore(t)
+
+		snapshot, err := store.Save("put", onepassword.Item{ID: "item"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// put the identity where earlier versions kept it
+		err = os.Rename(store.IdentityFile, filepath.Join(store.Dir, "identity.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.Load(snapshot.ID); err != nil {
+			t.Fatalf("Expected the snapshot to load with the moved identity, got %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(store.Dir, "identity.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected the identity to be moved out of the snapshot directory")
+		}
+	})
+}
+
+func TestDiffItems(t *testing.T) {
+	sectionID := "section"
+	field := func(title string, value string) onepassword.ItemField {
+		return onepassword.ItemField{ID: title, Title: title, Value: value, SectionID: &sectionID}
+	}
+	sections := []onepassword.ItemSection{{ID: sectionID, Title: "production"}}
+
+	from := &onepassword.Item{Sections: sections, Fields: []onepassword.ItemField{field("A", "1"), field("B", "2"), field("C", "3")}}
+	to := &onepassword.Item{Sections: sections, Fields: []onepassword.ItemField{field("A", "1"), field("B", "changed"), field("D", "4")}}
+
+	expected := []FieldChange{
+		{Section: "production", Title: "B", Kind: "changed"},
+		{Section: "production", Title: "C", Kind: "removed"},
+		{Section: "production", Title: "D", Kind: "added"},
+	}
+
+	changes := DiffItems(from, to)
+	if !slices.Equal(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	store := newSnapshotStore(t)
+	client = WithSnapshots(client, store)
+
+	item := newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+
+	environment := map[string]any{"A": "2", "B": "3"}
+	item, err := UpdateItem(ctx, client, item, "production", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var before *Snapshot
+	for _, snapshot := range snapshots {
+		if sectionValues(&snapshot.Item, "production")["A"] == "1" {
+			before = &snapshot
+			break
+		}
+	}
+	if before == nil {
+		t.Fatalf("Expected a snapshot of the item before the update, got %v", snapshots)
+	}
+
+	t.Run("changed item", func(t *testing.T) {
+		restored, err := RestoreSnapshot(ctx, client, before)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values := sectionValues(restored, "production")
+		if len(values) != 1 || values["A"] != "1" {
+			t.Errorf("Expected the item to be restored to A=1, got %v", values)
+		}
+	})
+
+	t.Run("deleted item", func(t *testing.T) {
+		err := client.Items().Delete(ctx, item.VaultID, item.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored, err := RestoreSnapshot(ctx, client, before)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := FindItemWithName(ctx, client, &onepassword.VaultOverview{ID: restored.VaultID}, "item")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values := sectionValues(found, "production")
+		if len(values) != 1 || values["A"] != "1" {
+			t.Errorf("Expected the item to be recreated with A=1, got %v", values)
+		}
+	})
+}
+
+// countingGets counts the item reads
+type countingGets struct {
+	onepassword.ItemsAPI
+	gets int
+}
+
+func (c *countingGets) Get(ctx context.Context, vaultID string, itemID string) (onepassword.Item, error) {
+	c.gets++
+	return c.ItemsAPI.Get(ctx, vaultID, itemID)
+}
+
+func TestSnapshotReusesStoredItem(t *testing.T) {
+	ctx := t.Context()
+	client, _ := NewMemoryClient("vault")
+	store := newSnapshotStore(t)
+
+	item := newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+
+	items := &countingGets{ItemsAPI: client.ItemsAPI}
+	client.ItemsAPI = items
+	client = WithSnapshots(client, store)
+
+	environment := map[string]any{"A": "2"}
+	if _, err := UpdateItem(ctx, client, item, "production", &environment); err != nil {
+		t.Fatal(err)
+	}
+
+	if items.gets != 1 {
+		t.Errorf("Expected the version check and the snapshot to share one read, got %d", items.gets)
+	}
+
+	snapshots, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected one snapshot, got %d", len(snapshots))
+	}
+
+	snapshot, err := store.Load(snapshots[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sectionValues(&snapshot.Item, "production")["A"] != "1" {
+		t.Errorf("Expected the snapshot to hold the stored item, got %+v", snapshot.Item)
+	}
+}
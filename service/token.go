@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TokenHelper resolves a service account token from somewhere other than the command line
+type TokenHelper func(ctx context.Context) (string, error)
+
+// ErrNoToken is returned when neither the token nor any of the helpers produced a token
+var ErrNoToken = errors.New("no service account token, set --service-account, OP_SERVICE_ACCOUNT_TOKEN, token_command, token_file or token_keyring")
+
+// ResolveToken returns the token if it is set, otherwise the first token produced by the helpers
+func ResolveToken(ctx context.Context, token string, helpers ...TokenHelper) (string, error) {
+	token = strings.TrimSpace(token)
+	if token != "" {
+		return token, nil
+	}
+
+	for _, helper := range helpers {
+		if helper == nil {
+			continue
+		}
+
+		resolved, err := helper(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		resolved = strings.TrimSpace(resolved)
+		if resolved != "" {
+			return resolved, nil
+		}
+	}
+
+	return "", ErrNoToken
+}
+
+// TokenFromCommand runs the command through the shell and reads the token from its stdout
+func TokenFromCommand(command string) TokenHelper {
+	return func(ctx context.Context) (string, error) {
+		if command == "" {
+			return "", nil
+		}
+
+		var stdout bytes.Buffer
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("token_command failed: %w", err)
+		}
+
+		return stdout.String(), nil
+	}
+}
+
+// TokenFromFile reads the token from a file, refusing files that can be read by other users
+func TokenFromFile(path string) TokenHelper {
+	return func(ctx context.Context) (string, error) {
+		if path == "" {
+			return "", nil
+		}
+
+		return ReadPrivateFile(path)
+	}
+}
+
+// TokenFromKeyring reads the token from a keyring, either the linux secret service ("secret-service")
+// via secret-tool, or a file keyring ("file") in the user config directory
+func TokenFromKeyring(backend string, account string) TokenHelper {
+	return func(ctx context.Context) (string, error) {
+		if account == "" {
+			account = "default"
+		}
+
+		switch backend {
+		case "":
+			return "", nil
+
+		case "secret-service":
+			var stdout bytes.Buffer
+			cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", "envop", "account", account)
+			cmd.Stdout = &stdout
+			cmd.Stderr = os.Stderr
+
+			if err := cmd.Run(); err != nil {
+				return "", fmt.Errorf("secret service lookup for %s failed: %w", account, err)
+			}
+			return stdout.String(), nil
+
+		case "file":
+			dir, err := os.UserConfigDir()
+			if err != nil {
+				return "", err
+			}
+			return ReadPrivateFile(filepath.Join(dir, "envop", "keyring", account))
+
+		default:
+			return "", fmt.Errorf("unknown token_keyring %s, expected secret-service or file", backend)
+		}
+	}
+}
+
+// ReadPrivateFile reads a file that must not be accessible to the group or other users
+func ReadPrivateFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("%s has permissions %#o, it must not be accessible by group or others (chmod 600)", path, info.Mode().Perm())
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(raw), nil
+}
+
+// TokenInfo is the part of a service account token that can be decoded locally, without the secrets
+type TokenInfo struct {
+	SignInAddress string
+	Email         string
+	DeviceUUID    string
+	AuthMethod    string
+	ExpiresAt     *time.Time
+}
+
+// DecodeToken decodes the service account token locally. Tokens do not always carry an expiry,
+// in which case ExpiresAt is nil.
+func DecodeToken(token string) (*TokenInfo, error) {
+	payload, ok := strings.CutPrefix(strings.TrimSpace(token), "ops_")
+	if !ok {
+		return nil, fmt.Errorf("not a service account token, expected an ops_ prefix")
+	}
+
+	var raw []byte
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.StdEncoding} {
+		raw, err = encoding.DecodeString(payload)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode service account token: %w", err)
+	}
+
+	var decoded struct {
+		SignInAddress string `json:"signInAddress"`
+		Email         string `json:"email"`
+		DeviceUUID    string `json:"deviceUuid"`
+		UserAuth      struct {
+			Method string `json:"method"`
+		} `json:"userAuth"`
+		Exp       int64  `json:"exp"`
+		ExpiresAt string `json:"expiresAt"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("could not decode service account token: %w", err)
+	}
+
+	info := &TokenInfo{
+		SignInAddress: decoded.SignInAddress,
+		Email:         decoded.Email,
+		DeviceUUID:    decoded.DeviceUUID,
+		AuthMethod:    decoded.UserAuth.Method,
+	}
+
+	if decoded.Exp > 0 {
+		expiresAt := time.Unix(decoded.Exp, 0)
+		info.ExpiresAt = &expiresAt
+	} else if decoded.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, decoded.ExpiresAt)
+		if err == nil {
+			info.ExpiresAt = &expiresAt
+		}
+	}
+
+	return info, nil
+}
+
+// ExpiresWithin reports whether the token is known to expire within the window
+func (t *TokenInfo) ExpiresWithin(window time.Duration) bool {
+	return t.ExpiresAt != nil && time.Until(*t.ExpiresAt) <= window
+}
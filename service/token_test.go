@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDecodeToken(t *testing.T) {
+	payload := `{"signInAddress":"example.1password.com","email":"svc@example.com","deviceUuid":"device","userAuth":{"method":"SRPg-4096"},"exp":1735732800}`
+
+	t.Run("valid", func(t *testing.T) {
+		info, err := DecodeToken("ops_" + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.SignInAddress != "example.1password.com" || info.Email != "svc@example.com" || info.DeviceUUID != "device" || info.AuthMethod != "SRPg-4096" {
+			t.Errorf("Unexpected token info %+v", info)
+		}
+		if info.ExpiresAt == nil || !info.ExpiresAt.Equal(time.Unix(1735732800, 0)) {
+			t.Errorf("Expected the token to expire at 1735732800, got %v", info.ExpiresAt)
+		}
+	})
+
+	t.Run("padded", func(t *testing.T) {
+		info, err := DecodeToken("ops_" + base64.StdEncoding.EncodeToString([]byte(`{"email":"svc@example.com"}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Email != "svc@example.com" || info.ExpiresAt != nil {
+			t.Errorf("Expected svc@example.com without an expiry, got %+v", info)
+		}
+	})
+
+	t.Run("missing prefix", func(t *testing.T) {
+		if _, err := DecodeToken(base64.RawURLEncoding.EncodeToString([]byte(payload))); err == nil {
+			t.Errorf("Expected a token without the ops_ prefix to be refused")
+		}
+	})
+
+	t.Run("bad base64", func(t *testing.T) {
+		if _, err := DecodeToken("ops_not*base64!"); err == nil {
+			t.Errorf("Expected a token that isn't base64 to be refused")
+		}
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		if _, err := DecodeToken("ops_" + base64.RawURLEncoding.EncodeToString([]byte("token"))); err == nil {
+			t.Errorf("Expected a token that isn't json to be refused")
+		}
+	})
+}
+
+func TestTokenFromFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there are no unix permissions on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(path, []byte("ops_file\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TokenFromFile(path)(t.Context()); err == nil {
+		t.Errorf("Expected a world readable token file to be refused")
+	}
+
+	err = os.Chmod(path, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := ResolveToken(t.Context(), "", TokenFromFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "ops_file" {
+		t.Errorf("Expected ops_file, got %q", token)
+	}
+}
+
+func TestTokenFromKeyringFile(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the config directory is only set by XDG_CONFIG_HOME on linux")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	err := os.MkdirAll(filepath.Join(dir, "envop", "keyring"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "envop", "keyring", "ci"), []byte("ops_keyring\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := ResolveToken(t.Context(), "", TokenFromKeyring("file", "ci"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "ops_keyring" {
+		t.Errorf("Expected ops_keyring, got %q", token)
+	}
+
+	if _, err := TokenFromKeyring("unknown", "ci")(t.Context()); err == nil {
+		t.Errorf("Expected an unknown keyring to be refused")
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token_command runs with /bin/sh")
+	}
+
+	failing := func(ctx context.Context) (string, error) {
+		return "", errors.New("should not run")
+	}
+
+	t.Run("command output is trimmed", func(t *testing.T) {
+		token, err := ResolveToken(t.Context(), "", TokenFromCommand("printf '  ops_command\\n\\n'"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "ops_command" {
+			t.Errorf("Expected ops_command, got %q", token)
+		}
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		if _, err := ResolveToken(t.Context(), "", TokenFromCommand("exit 1")); err == nil {
+			t.Errorf("Expected a failing token_command to be reported")
+		}
+	})
+
+	t.Run("the token wins", func(t *testing.T) {
+		token, err := ResolveToken(t.Context(), " ops_flag ", failing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "ops_flag" {
+			t.Errorf("Expected ops_flag, got %q", token)
+		}
+	})
+
+	t.Run("the first helper with a token wins", func(t *testing.T) {
+		token, err := ResolveToken(t.Context(), "", TokenFromCommand(""), nil, TokenFromCommand("echo ops_second"), failing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "ops_second" {
+			t.Errorf("Expected ops_second, got %q", token)
+		}
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		_, err := ResolveToken(t.Context(), "", TokenFromCommand(""), TokenFromFile(""), TokenFromKeyring("", ""))
+		if !errors.Is(err, ErrNoToken) {
+			t.Errorf("Expected ErrNoToken, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/1password/onepassword-sdk-go"
+)
+
+// Watch polls sections of an item for changes
+type Watch struct {
+	Vault    string
+	Item     string
+	Sections []string
+	Interval time.Duration
+	// Failed is called when a poll fails, the watch carries on with the next poll
+	Failed func(err error)
+}
+
+// Run polls until the context is done, calling changed whenever the environment differs from the last one
+// seen, starting with last. An error from changed stops the watch.
+func (w Watch) Run(ctx context.Context, client *onepassword.Client, last map[string]any, changed func(env map[string]any) error) error {
+	if w.Interval <= 0 {
+		return fmt.Errorf("the watch interval must be more than 0, got %s", w.Interval)
+	}
+
+	lastJSON, err := json.Marshal(last)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		env, err := ReadSections(ctx, client, w.Vault, w.Item, w.Sections...)
+		if err == nil {
+			var envJSON []byte
+			envJSON, err = json.Marshal(env)
+			if err == nil && string(envJSON) != string(lastJSON) {
+				lastJSON = envJSON
+				err = changed(env)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if err != nil && ctx.Err() == nil && w.Failed != nil {
+			w.Failed(err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	client, _ := NewMemoryClient("vault")
+	item := newItemWithSection(t, ctx, client, "item", "production", map[string]any{"A": "1"})
+
+	watcher := Watch{Vault: "vault", Item: "item", Sections: []string{"production"}, Interval: 10 * time.Millisecond}
+
+	changes := make(chan map[string]any, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Run(ctx, client, map[string]any{"A": int64(1)}, func(env map[string]any) error {
+			changes <- env
+			cancel()
+			return nil
+		})
+	}()
+
+	environment := map[string]any{"A": "2"}
+	_, err := UpdateItem(ctx, client, item, "production", &environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case env := <-changes:
+		if env["A"] != int64(2) {
+			t.Errorf("Expected A to be 2, got %v", env["A"])
+		}
+	default:
+		t.Errorf("Expected the change to be reported")
+	}
+}
+
+func TestWatchInterval(t *testing.T) {
+	client, _ := NewMemoryClient("vault")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		watcher := Watch{Vault: "vault", Item: "item", Sections: []string{"production"}, Interval: interval}
+
+		err := watcher.Run(t.Context(), client, nil, func(env map[string]any) error { return nil })
+		if err == nil {
+			t.Errorf("Expected an interval of %s to be refused", interval)
+		}
+	}
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+
+	err := WriteAtomic(path, "env", map[string]any{"A": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(raw) != "A=\"1\"\n" {
+		t.Errorf("Unexpected file content %q", raw)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be renamed, got %v", entries)
+	}
+
+	if err := WriteAtomic(path, "yaml", map[string]any{}); err == nil {
+		t.Errorf("Expected an unknown format to fail")
+	}
+}